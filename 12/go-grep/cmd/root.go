@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 	"wb-tech-l2/12/go-grep/internal/config"
 	"wb-tech-l2/12/go-grep/internal/grep"
+	"wb-tech-l2/12/go-grep/internal/reader"
 
 	"github.com/spf13/cobra"
 )
@@ -17,6 +21,7 @@ var (
 A pattern search utility that supports various grep options similar to Unix grep command.
 Supports regular expressions, context lines, ignore case, inverse match and more.
 `
+	stdinName = `(standard input)`
 	appConfig = new(config.Grep)
 	rootCmd   = &cobra.Command{
 		Use:   appName,
@@ -33,6 +38,21 @@ func init() {
 	rootCmd.Flags().StringVarP(&appConfig.Pattern, "pattern", "e", "", "pattern to search for (required)")
 	rootCmd.Flags().StringVarP(&appConfig.FilePath, "file", "f", "", "read from file (default: stdin)")
 
+	// Compressed input flags
+	rootCmd.Flags().BoolVarP(&appConfig.SearchZip, "search-zip", "z", false, "decompress gzip, bzip2, zstd and xz input")
+	rootCmd.Flags().BoolVarP(&appConfig.SearchArchive, "archive", "", false, "search members of tar and zip archives, and with -z of tar.gz ones")
+	rootCmd.Flags().BoolVarP(&appConfig.Follow, "follow", "", false, "keep reading the -f file as it grows (Ctrl+C to stop)")
+
+	// Boolean query flags
+	rootCmd.Flags().StringArrayVarP(&appConfig.And, "and", "", nil, "line must also match PATTERN (repeatable)")
+	rootCmd.Flags().StringArrayVarP(&appConfig.Or, "or", "", nil, "line may match PATTERN instead (repeatable)")
+	rootCmd.Flags().StringArrayVarP(&appConfig.Not, "not", "", nil, "line must not match PATTERN (repeatable)")
+	rootCmd.Flags().StringVarP(&appConfig.Expr, "expr", "", "", "boolean expression of patterns, e.g. 'ERROR && !healthcheck'")
+
+	// Structured record flags
+	rootCmd.Flags().StringVarP(&appConfig.Format, "format", "", "", "treat lines as records: jsonl or csv")
+	rootCmd.Flags().StringVarP(&appConfig.Field, "field", "", "", "match only this field (.json.path, csv column name or number)")
+
 	// Context flags
 	rootCmd.Flags().IntVarP(&appConfig.AfterContext, "after-context", "A", 0, "print N lines after match")
 	rootCmd.Flags().IntVarP(&appConfig.BeforeContext, "before-context", "B", 0, "print N lines before match")
@@ -44,6 +64,13 @@ func init() {
 	rootCmd.Flags().BoolVarP(&appConfig.InvertMatch, "invert-match", "v", false, "select non-matching lines")
 	rootCmd.Flags().BoolVarP(&appConfig.FixedString, "fixed-strings", "F", false, "interpret pattern as fixed string")
 	rootCmd.Flags().BoolVarP(&appConfig.LineNumber, "line-number", "n", false, "print line number with output")
+	rootCmd.Flags().BoolVarP(&appConfig.Multiline, "multiline", "U", false, "let matches span multiple lines")
+
+	// Substitution flags
+	rootCmd.Flags().StringVarP(&appConfig.Replace, "replace", "", "", "print matches replaced by TEMPLATE ($1, ${name} expand groups)")
+	rootCmd.Flags().BoolVarP(&appConfig.InPlace, "in-place", "", false, "write replacements back to the -f file (one regular file, no directories)")
+	rootCmd.Flags().BoolVarP(&appConfig.Backup, "backup", "", false, "keep the original file as FILE.bak with --in-place")
+	rootCmd.Flags().BoolVarP(&appConfig.DryRun, "dry-run", "", false, "show a unified diff instead of writing with --in-place")
 
 	rootCmd.SetHelpCommand(&cobra.Command{
 		Use:   "help [command]",
@@ -60,6 +87,13 @@ func exitWithErrorMessage(message string) {
 }
 
 func mustSetupPattern(args []string) {
+	if appConfig.Expr != "" {
+		if appConfig.Pattern != "" || len(args) > 0 {
+			exitWithErrorMessage("use either a pattern or --expr")
+		}
+		return
+	}
+
 	if appConfig.Pattern == "" && len(args) > 0 {
 		appConfig.Pattern = args[0]
 	}
@@ -69,10 +103,38 @@ func mustSetupPattern(args []string) {
 	}
 }
 
-func runApp(_ *cobra.Command, args []string) {
+func runApp(cmd *cobra.Command, args []string) {
+	operands := args
+	if appConfig.Pattern == "" && appConfig.Expr == "" && len(args) > 0 {
+		operands = args[1:]
+	}
 	mustSetupPattern(args)
+	if len(operands) > 0 {
+		// One input is searched, the -f file or stdin; further paths
+		// would otherwise be dropped without a word.
+		exitWithErrorMessage(fmt.Sprintf("unexpected argument %q: give the input file with -f", operands[0]))
+	}
+	appConfig.HasReplace = cmd.Flags().Changed("replace")
+
+	if appConfig.InPlace {
+		runInPlace()
+		return
+	}
+
+	if appConfig.Follow {
+		runFollow()
+		return
+	}
+
+	if appConfig.FilePath != "" && !appConfig.SearchZip && !appConfig.SearchArchive {
+		if err := grep.NewService(appConfig).ProcessFile(appConfig.FilePath, os.Stdout); err != nil {
+			exitWithErrorMessage(err.Error())
+		}
+		return
+	}
 
 	var reader io.ReadCloser
+	name := stdinName
 	if appConfig.FilePath == "" {
 		reader = os.Stdin
 		fmt.Println("Reading text from STDIN. Enter text (press Ctrl+D to finish):")
@@ -83,13 +145,67 @@ func runApp(_ *cobra.Command, args []string) {
 		}
 		defer func() { _ = file.Close() }()
 		reader = file
+		name = appConfig.FilePath
 	}
 
-	if err := grep.NewService(appConfig).Process(reader, os.Stdout); err != nil {
+	if err := process(name, reader, os.Stdout); err != nil {
 		exitWithErrorMessage(err.Error())
 	}
 }
 
+func runInPlace() {
+	if appConfig.FilePath == "" {
+		exitWithErrorMessage("--in-place requires a file (-f)")
+	}
+	if !appConfig.HasReplace {
+		exitWithErrorMessage("--in-place requires --replace")
+	}
+
+	if err := grep.NewService(appConfig).Substitute(appConfig.FilePath, os.Stdout); err != nil {
+		exitWithErrorMessage(err.Error())
+	}
+}
+
+func runFollow() {
+	if appConfig.FilePath == "" {
+		exitWithErrorMessage("--follow requires a file (-f)")
+	}
+	if appConfig.SearchZip || appConfig.SearchArchive {
+		exitWithErrorMessage("--follow cannot be combined with -z or --archive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := grep.NewService(appConfig).Follow(ctx, appConfig.FilePath, os.Stdout); err != nil {
+		exitWithErrorMessage(err.Error())
+	}
+}
+
+func process(name string, r io.Reader, w io.Writer) error {
+	svc := grep.NewService(appConfig)
+
+	if appConfig.SearchArchive {
+		return reader.WalkArchive(r, appConfig.SearchZip, func(member string, mr io.Reader) error {
+			if member == "" {
+				return svc.Process(mr, w)
+			}
+			return svc.ProcessNamed(name+":"+member, mr, w)
+		})
+	}
+
+	if appConfig.SearchZip {
+		dr, err := reader.Decompress(r)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dr.Close() }()
+		r = dr
+	}
+
+	return svc.Process(r, w)
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
@@ -3,8 +3,21 @@ package config
 type Grep struct {
 	Pattern string
 
+	// Boolean query settings
+	And  []string // --and
+	Or   []string // --or
+	Not  []string // --not
+	Expr string   // --expr
+
 	// Reader settings
-	FilePath string // -f
+	FilePath      string // -f
+	SearchZip     bool   // -z
+	SearchArchive bool   // --archive
+	Follow        bool   // --follow
+
+	// Record settings
+	Format string // --format
+	Field  string // --field
 
 	// Context settings
 	AfterContext  int // -A
@@ -17,4 +30,12 @@ type Grep struct {
 	InvertMatch bool // -v
 	FixedString bool // -F
 	LineNumber  bool // -n
+	Multiline   bool // -U
+
+	// Substitution settings
+	Replace    string // --replace
+	HasReplace bool   // set when --replace is given, the template may be empty
+	InPlace    bool   // --in-place
+	Backup     bool   // --backup
+	DryRun     bool   // --dry-run
 }
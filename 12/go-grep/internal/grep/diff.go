@@ -0,0 +1,77 @@
+package grep
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContext = 3
+
+type hunk struct {
+	start, end int // line range [start, end) shown in the hunk
+}
+
+// writeUnifiedDiff prints a unified diff between two versions of a file.
+// Substitutions never add or remove lines, so line i of oldLines always
+// corresponds to line i of newLines. Lines keep their endings.
+func writeUnifiedDiff(w io.Writer, path string, oldLines, newLines []string) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = fmt.Fprintf(bw, "--- %s\n+++ %s\n", path, path)
+	for _, h := range diffHunks(oldLines, newLines) {
+		size := h.end - h.start
+		_, _ = fmt.Fprintf(bw, "@@ -%d,%d +%d,%d @@\n", h.start+1, size, h.start+1, size)
+
+		for i := h.start; i < h.end; {
+			if oldLines[i] == newLines[i] {
+				writeDiffLine(bw, " ", oldLines[i])
+				i++
+				continue
+			}
+
+			j := i
+			for j < h.end && oldLines[j] != newLines[j] {
+				j++
+			}
+			for k := i; k < j; k++ {
+				writeDiffLine(bw, "-", oldLines[k])
+			}
+			for k := i; k < j; k++ {
+				writeDiffLine(bw, "+", newLines[k])
+			}
+			i = j
+		}
+	}
+
+	return bw.Flush()
+}
+
+func diffHunks(oldLines, newLines []string) []hunk {
+	var hunks []hunk
+	for i := range oldLines {
+		if oldLines[i] == newLines[i] {
+			continue
+		}
+
+		start := max(i-diffContext, 0)
+		end := min(i+1+diffContext, len(oldLines))
+
+		if n := len(hunks); n > 0 && start <= hunks[n-1].end {
+			hunks[n-1].end = end
+			continue
+		}
+		hunks = append(hunks, hunk{start: start, end: end})
+	}
+	return hunks
+}
+
+func writeDiffLine(w *bufio.Writer, marker, line string) {
+	_, _ = w.WriteString(marker)
+	if strings.HasSuffix(line, "\n") {
+		_, _ = w.WriteString(line)
+		return
+	}
+	_, _ = w.WriteString(line + "\n\\ No newline at end of file\n")
+}
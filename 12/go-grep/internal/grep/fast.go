@@ -0,0 +1,136 @@
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"wb-tech-l2/12/go-grep/internal/reader"
+)
+
+const fastWriterSize = 64 * 1024
+
+// ProcessFile greps a regular file. Literal and literal-prefixed patterns
+// are searched over the whole mapped file at once, other patterns and
+// options fall back to Process.
+func (s *Service) ProcessFile(path string, w io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	lit, re, ok := s.literalPattern()
+	if !ok {
+		return s.Process(file, w)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return s.Process(file, w)
+	}
+
+	data, release, err := reader.MapFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	defer func() { _ = release() }()
+
+	return s.scanLiteral(data, lit, re, w)
+}
+
+// literalPattern reports whether the fast path can be used. It returns the
+// literal to look for and, for literal-prefixed patterns, the regexp that
+// confirms a candidate line.
+func (s *Service) literalPattern() ([]byte, *regexp.Regexp, bool) {
+	if s.cfg.InvertMatch || s.cfg.IgnoreCase || s.cfg.HasReplace || s.cfg.Multiline {
+		return nil, nil, false
+	}
+	if s.cfg.Context > 0 || s.cfg.AfterContext > 0 || s.cfg.BeforeContext > 0 {
+		return nil, nil, false
+	}
+	if s.cfg.Format != "" || s.hasQuery() || s.cfg.Pattern == "" || strings.ContainsAny(s.cfg.Pattern, "\r\n") {
+		return nil, nil, false
+	}
+
+	if s.cfg.FixedString {
+		return []byte(s.cfg.Pattern), nil, true
+	}
+
+	re, err := regexp.Compile(s.cfg.Pattern)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	prefix, complete := re.LiteralPrefix()
+	if prefix == "" {
+		return nil, nil, false
+	}
+	if complete {
+		return []byte(prefix), nil, true
+	}
+	return []byte(prefix), re, true
+}
+
+// scanLiteral finds candidate hits with bytes.Index and only looks for line
+// boundaries around them, so lines without the literal are never visited.
+func (s *Service) scanLiteral(data, lit []byte, re *regexp.Regexp, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, fastWriterSize)
+
+	count := 0
+	lineNum := 1 // number of the line starting at counted
+	counted := 0
+
+	pos := 0
+	for pos < len(data) {
+		i := bytes.Index(data[pos:], lit)
+		if i < 0 {
+			break
+		}
+		hit := pos + i
+
+		start := pos + bytes.LastIndexByte(data[pos:hit], '\n') + 1
+		end := len(data)
+		if j := bytes.IndexByte(data[hit:], '\n'); j >= 0 {
+			end = hit + j
+		}
+		pos = end + 1
+
+		line := bytes.TrimSuffix(data[start:end], []byte{'\r'})
+		if re != nil && !re.Match(line) {
+			continue
+		}
+
+		count++
+		if s.cfg.CountOnly {
+			continue
+		}
+
+		if s.cfg.LineNumber {
+			lineNum += bytes.Count(data[counted:start], []byte{'\n'})
+			counted = start
+
+			_, _ = bw.WriteString(strconv.Itoa(lineNum))
+			_ = bw.WriteByte(':')
+		}
+		_, _ = bw.Write(line)
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.CountOnly {
+		if err := s.printCount(bw, "", count); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
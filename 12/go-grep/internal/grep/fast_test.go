@@ -0,0 +1,191 @@
+package grep
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"wb-tech-l2/12/go-grep/internal/config"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	return path
+}
+
+func TestService_ProcessFile_MatchesProcess(t *testing.T) {
+	content := "first line\nERROR: disk full\r\nok\n\nERROR again ERROR\nlast ERROR: no newline"
+
+	tests := []struct {
+		name string
+		cfg  config.Grep
+	}{
+		{"fixed string", config.Grep{Pattern: "ERROR", FixedString: true}},
+		{"literal regexp", config.Grep{Pattern: "ERROR"}},
+		{"literal prefix regexp", config.Grep{Pattern: `ERROR:\s+\w+`}},
+		{"line numbers", config.Grep{Pattern: "ERROR", LineNumber: true}},
+		{"count", config.Grep{Pattern: "ERROR", CountOnly: true}},
+		{"no matches", config.Grep{Pattern: "missing", LineNumber: true}},
+		{"slow path", config.Grep{Pattern: "error", IgnoreCase: true, Context: 1}},
+	}
+
+	path := writeTempFile(t, content)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			service := NewService(&cfg)
+
+			expected := &strings.Builder{}
+			if err := service.Process(strings.NewReader(content), expected); err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+
+			got := &strings.Builder{}
+			if err := service.ProcessFile(path, got); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+
+			if got.String() != expected.String() {
+				t.Errorf("Expected:\n%q\nGot:\n%q", expected.String(), got.String())
+			}
+		})
+	}
+}
+
+func TestService_literalPattern(t *testing.T) {
+	tests := []struct {
+		cfg      config.Grep
+		literal  string
+		hasRegex bool
+		ok       bool
+	}{
+		{config.Grep{Pattern: "a.b", FixedString: true}, "a.b", false, true},
+		{config.Grep{Pattern: "hello"}, "hello", false, true},
+		{config.Grep{Pattern: `hello\d+`}, "hello", true, true},
+		{config.Grep{Pattern: `^hello`}, "hello", true, true},
+		{config.Grep{Pattern: "hello", IgnoreCase: true}, "", false, false},
+		{config.Grep{Pattern: "hello", InvertMatch: true}, "", false, false},
+		{config.Grep{Pattern: "hello", AfterContext: 2}, "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cfg.Pattern, func(t *testing.T) {
+			cfg := tt.cfg
+			lit, re, ok := NewService(&cfg).literalPattern()
+			if ok != tt.ok || string(lit) != tt.literal || (re != nil) != tt.hasRegex {
+				t.Errorf("Got (%q, %v, %v), expected (%q, regexp=%v, %v)", lit, re, ok, tt.literal, tt.hasRegex, tt.ok)
+			}
+		})
+	}
+}
+
+// The benchmarks grep a generated log file of GO_GREP_BENCH_SIZE bytes
+// (1GB by default). The file is cached in the temp directory between runs.
+
+var (
+	benchOnce sync.Once
+	benchPath string
+	benchErr  error
+)
+
+func benchFile(b *testing.B) string {
+	b.Helper()
+
+	benchOnce.Do(func() {
+		size := int64(1 << 30)
+		if env := os.Getenv("GO_GREP_BENCH_SIZE"); env != "" {
+			size, benchErr = strconv.ParseInt(env, 10, 64)
+			if benchErr != nil {
+				return
+			}
+		}
+
+		benchPath = filepath.Join(os.TempDir(), fmt.Sprintf("go-grep-bench-%d.log", size))
+		if info, err := os.Stat(benchPath); err == nil && info.Size() >= size {
+			return
+		}
+		benchErr = generateLog(benchPath, size)
+	})
+
+	if benchErr != nil {
+		b.Fatalf("Failed to prepare benchmark file: %v", benchErr)
+	}
+	return benchPath
+}
+
+func generateLog(path string, size int64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	w := bufio.NewWriter(file)
+	var written int64
+	for i := 0; written < size; i++ {
+		level := "INFO"
+		if i%1000 == 0 {
+			level = "ERROR"
+		}
+		n, err := fmt.Fprintf(w, "2025-01-01T00:00:%02d %s request id=%d served in %dms\n", i%60, level, i, i%250)
+		if err != nil {
+			return err
+		}
+		written += int64(n)
+	}
+	return w.Flush()
+}
+
+func benchmarkGrep(b *testing.B, cfg config.Grep, fast bool) {
+	path := benchFile(b)
+	info, err := os.Stat(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	service := NewService(&cfg)
+	b.SetBytes(info.Size())
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if fast {
+			err = service.ProcessFile(path, io.Discard)
+		} else {
+			var file *os.File
+			if file, err = os.Open(path); err != nil {
+				b.Fatal(err)
+			}
+			err = service.Process(file, io.Discard)
+			_ = file.Close()
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProcess_Literal(b *testing.B) {
+	benchmarkGrep(b, config.Grep{Pattern: "ERROR", LineNumber: true}, false)
+}
+
+func BenchmarkProcessFile_Literal(b *testing.B) {
+	benchmarkGrep(b, config.Grep{Pattern: "ERROR", LineNumber: true}, true)
+}
+
+func BenchmarkProcess_LiteralPrefix(b *testing.B) {
+	benchmarkGrep(b, config.Grep{Pattern: `ERROR request id=\d+0 `}, false)
+}
+
+func BenchmarkProcessFile_LiteralPrefix(b *testing.B) {
+	benchmarkGrep(b, config.Grep{Pattern: `ERROR request id=\d+0 `}, true)
+}
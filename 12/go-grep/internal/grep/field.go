@@ -0,0 +1,170 @@
+package grep
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+)
+
+var (
+	errFieldRequired   = errors.New("--format requires --field")
+	errFieldWithoutFmt = errors.New("--field requires --format")
+	errFormatMultiline = errors.New("--format cannot be combined with multiline mode")
+	errFormatReplace   = errors.New("--format cannot be combined with --replace")
+	errUnknownColumn   = errors.New("no such column in the CSV header")
+)
+
+// fieldExtractor returns the function that picks the searched value out
+// of a record, or nil when records are plain lines. The second result is
+// false when the record has no such field or cannot be parsed. When the
+// input starts with a header, header is the function that reads it.
+func (s *Service) fieldExtractor() (extract func(string) (string, bool), header func(string) error, err error) {
+	if s.cfg.Format == "" {
+		if s.cfg.Field != "" {
+			return nil, nil, errFieldWithoutFmt
+		}
+		return nil, nil, nil
+	}
+
+	if s.cfg.Field == "" {
+		return nil, nil, errFieldRequired
+	}
+
+	switch s.cfg.Format {
+	case formatJSONL:
+		path, err := parseJSONPath(s.cfg.Field)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(line string) (string, bool) {
+			return extractJSONField(line, path)
+		}, nil, nil
+	case formatCSV:
+		extract, header = newCSVExtractor(s.cfg.Field)
+		return extract, header, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q (want %s or %s)", s.cfg.Format, formatJSONL, formatCSV)
+	}
+}
+
+// parseJSONPath splits paths like .user.name or .items[0].id into keys and
+// array indexes.
+func parseJSONPath(path string) ([]any, error) {
+	if !strings.HasPrefix(path, ".") {
+		return nil, fmt.Errorf("invalid field path %q: must start with '.'", path)
+	}
+
+	var segments []any
+	for _, part := range strings.Split(path[1:], ".") {
+		key, rest, indexed := strings.Cut(part, "[")
+		if key != "" {
+			segments = append(segments, key)
+		}
+
+		for indexed {
+			idx, tail, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid field path %q: unclosed '['", path)
+			}
+			n, err := strconv.Atoi(idx)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid field path %q: bad index %q", path, idx)
+			}
+			segments = append(segments, n)
+
+			if tail != "" && !strings.HasPrefix(tail, "[") {
+				return nil, fmt.Errorf("invalid field path %q: unexpected %q", path, tail)
+			}
+			rest, indexed = strings.CutPrefix(tail, "[")
+		}
+	}
+
+	return segments, nil
+}
+
+func extractJSONField(line string, path []any) (string, bool) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return "", false
+	}
+
+	for _, segment := range path {
+		switch key := segment.(type) {
+		case string:
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			if value, ok = obj[key]; !ok {
+				return "", false
+			}
+		case int:
+			arr, ok := value.([]any)
+			if !ok || key >= len(arr) {
+				return "", false
+			}
+			value = arr[key]
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return "", false
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), true
+	}
+}
+
+// newCSVExtractor selects a column by 1-based number or by header name.
+// With a header name the first record is the header, which header reads
+// to find the column. It is not a record itself.
+func newCSVExtractor(field string) (extract func(string) (string, bool), header func(string) error) {
+	column, err := strconv.Atoi(field)
+	if err != nil || column < 1 {
+		header = func(line string) error {
+			record, _ := readCSVRecord(line)
+			for i, name := range record {
+				if strings.TrimSpace(name) == field {
+					column = i + 1
+					return nil
+				}
+			}
+			return fmt.Errorf("%w: %q", errUnknownColumn, field)
+		}
+	}
+
+	return func(line string) (string, bool) {
+		record, err := readCSVRecord(line)
+		if err != nil || column < 1 || column > len(record) {
+			return "", false
+		}
+		return record[column-1], true
+	}, header
+}
+
+func readCSVRecord(line string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(line))
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+	return r.Read()
+}
@@ -0,0 +1,160 @@
+package grep
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"wb-tech-l2/12/go-grep/internal/config"
+)
+
+func TestService_Process_JSONLField(t *testing.T) {
+	input := strings.Join([]string{
+		`{"level":"info","msg":"error budget ok","user":{"name":"ann"}}`,
+		`{"level":"error","msg":"disk full","user":{"name":"bob"}}`,
+		`not json`,
+		`{"level":"error","msg":"timeout","tags":["db","slow"]}`,
+		`{"msg":"no level"}`,
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		cfg      config.Grep
+		expected string
+	}{
+		{
+			name:     "match on field only",
+			cfg:      config.Grep{Pattern: "^error$", Field: ".level", LineNumber: true},
+			expected: "2:" + `{"level":"error","msg":"disk full","user":{"name":"bob"}}` + "\n4:" + `{"level":"error","msg":"timeout","tags":["db","slow"]}` + "\n",
+		},
+		{
+			name:     "nested field",
+			cfg:      config.Grep{Pattern: "bob", Field: ".user.name", CountOnly: true},
+			expected: "1\n",
+		},
+		{
+			name:     "array index",
+			cfg:      config.Grep{Pattern: "slow", Field: ".tags[1]", CountOnly: true},
+			expected: "1\n",
+		},
+		{
+			name:     "invert keeps records without the field",
+			cfg:      config.Grep{Pattern: "^error$", Field: ".level", InvertMatch: true, CountOnly: true},
+			expected: "3\n",
+		},
+		{
+			name:     "context",
+			cfg:      config.Grep{Pattern: "timeout", Field: ".msg", BeforeContext: 1, LineNumber: true},
+			expected: "3-not json\n4:" + `{"level":"error","msg":"timeout","tags":["db","slow"]}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Format = formatJSONL
+			output := &strings.Builder{}
+			if err := NewService(&cfg).Process(strings.NewReader(input), output); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestService_Process_CSVField(t *testing.T) {
+	input := "name,email,note\nann,ann@example.com,\"likes, commas\"\nbob,bob@test.org,commas\n"
+
+	tests := []struct {
+		field    string
+		pattern  string
+		expected string
+	}{
+		{"note", "commas", "ann,ann@example.com,\"likes, commas\"\nbob,bob@test.org,commas\n"},
+		{"email", `example\.com$`, "ann,ann@example.com,\"likes, commas\"\n"},
+		{"1", "^name$", "name,email,note\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			cfg := &config.Grep{Pattern: tt.pattern, Format: formatCSV, Field: tt.field}
+			output := &strings.Builder{}
+			if err := NewService(cfg).Process(strings.NewReader(input), output); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestParseJSONPath_Invalid(t *testing.T) {
+	for _, path := range []string{"level", ".items[", ".items[x]"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("Expected error for %q", path)
+		}
+	}
+}
+
+func TestService_Process_CSVHeader(t *testing.T) {
+	input := "name,level\na,error\nb,info\n"
+
+	tests := []struct {
+		name     string
+		cfg      config.Grep
+		expected string
+	}{
+		{"invert skips the header", config.Grep{Pattern: "error", Field: "level", InvertMatch: true}, "b,info\n"},
+		{"count skips the header", config.Grep{Pattern: "error", Field: "level", InvertMatch: true, CountOnly: true}, "1\n"},
+		{"header can be context", config.Grep{Pattern: "error", Field: "level", BeforeContext: 1, LineNumber: true}, "1-name,level\n2:a,error\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Format = formatCSV
+			output := &strings.Builder{}
+			if err := NewService(&cfg).Process(strings.NewReader(input), output); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestService_Process_CSVUnknownColumn(t *testing.T) {
+	cfg := &config.Grep{Pattern: "x", Format: formatCSV, Field: "missing"}
+	err := NewService(cfg).Process(strings.NewReader("name,level\nx,y\n"), &strings.Builder{})
+	if !errors.Is(err, errUnknownColumn) {
+		t.Errorf("Expected errUnknownColumn, got %v", err)
+	}
+}
+
+func TestLineStream_CSVHeaderAfterReset(t *testing.T) {
+	cfg := &config.Grep{Pattern: "^b$", Format: formatCSV, Field: "name"}
+	service := NewService(cfg)
+	matcher, header, err := service.buildMatcher()
+	if err != nil {
+		t.Fatalf("Failed to build matcher: %v", err)
+	}
+
+	got := &strings.Builder{}
+	stream := service.newLineStream(got, "", matcher)
+	stream.header = header
+	for _, line := range []string{"name,level", "b,1"} {
+		_ = stream.feed(line)
+	}
+	stream.reset()
+	for _, line := range []string{"level,name", "2,b"} {
+		_ = stream.feed(line)
+	}
+
+	expected := "b,1\n2,b\n"
+	if got.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, got.String())
+	}
+}
@@ -0,0 +1,39 @@
+package grep
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"wb-tech-l2/12/go-grep/internal/reader"
+)
+
+const followInterval = 250 * time.Millisecond
+
+// Follow greps the file at path and keeps matching lines appended to it
+// until ctx is done. Line numbers start over when the file is truncated or
+// rotated. With CountOnly the total is printed once following stops.
+func (s *Service) Follow(ctx context.Context, path string, w io.Writer) error {
+	if s.cfg.Multiline {
+		return errMultilineFollow
+	}
+
+	matcher, header, err := s.buildMatcher()
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	stream := s.newLineStream(w, "", matcher)
+	stream.header = header
+	if stream.replace, err = s.buildReplacer(); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if err = reader.NewTailer(path, followInterval).Run(ctx, stream.feed, stream.reset); err != nil {
+		return err
+	}
+
+	if s.cfg.CountOnly {
+		return s.printCount(w, "", stream.count)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package grep
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	errMultilineReplace = errors.New("multiline mode cannot be combined with --replace")
+	errMultilineFollow  = errors.New("multiline mode cannot be combined with --follow")
+)
+
+// compileMultiline compiles the pattern for matching against the whole
+// input, with ^ and $ anchored at line boundaries.
+func (s *Service) compileMultiline() (*regexp.Regexp, error) {
+	if s.cfg.HasReplace {
+		return nil, errMultilineReplace
+	}
+	if s.cfg.Format != "" {
+		return nil, errFormatMultiline
+	}
+	if s.hasQuery() {
+		return nil, errQueryMultiline
+	}
+
+	re, err := s.compilePattern()
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile("(?m)" + re.String())
+}
+
+// matchMultiline runs re over the lines joined back together and marks
+// every line a match touches.
+func (s *Service) matchMultiline(lines []string, re *regexp.Regexp) ([]bool, int) {
+	text := strings.Join(lines, "\n")
+
+	starts := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		starts[i] = offset
+		offset += len(line) + 1
+	}
+
+	lineAt := func(pos int) int {
+		return sort.Search(len(starts), func(i int) bool { return starts[i] > pos }) - 1
+	}
+
+	hit := make([]bool, len(lines))
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		first := lineAt(m[0])
+		if first < 0 {
+			continue
+		}
+
+		last := first
+		if m[1] > m[0] {
+			last = lineAt(m[1] - 1)
+		}
+		for i := first; i <= last; i++ {
+			hit[i] = true
+		}
+	}
+
+	count := 0
+	for i := range hit {
+		if s.cfg.InvertMatch {
+			hit[i] = !hit[i]
+		}
+		if hit[i] {
+			count++
+		}
+	}
+
+	return hit, count
+}
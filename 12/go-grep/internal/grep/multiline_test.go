@@ -0,0 +1,65 @@
+package grep
+
+import (
+	"strings"
+	"testing"
+	"wb-tech-l2/12/go-grep/internal/config"
+)
+
+func TestService_Process_Multiline(t *testing.T) {
+	input := strings.Join([]string{
+		"package main",
+		"",
+		"func answer() {",
+		"\treturn 42",
+		"}",
+		"",
+		"func noop() {",
+		"}",
+	}, "\n")
+
+	tests := []struct {
+		name     string
+		cfg      config.Grep
+		expected string
+	}{
+		{
+			name:     "match spans lines",
+			cfg:      config.Grep{Pattern: `func\s+\w+\(\)\s*\{\n\s*return`, Multiline: true, LineNumber: true},
+			expected: "3:func answer() {\n4:\treturn 42\n",
+		},
+		{
+			name:     "context around multiline match",
+			cfg:      config.Grep{Pattern: `\{\n\s*return`, Multiline: true, LineNumber: true, Context: 1},
+			expected: "2-\n3:func answer() {\n4:\treturn 42\n5-}\n",
+		},
+		{
+			name:     "anchors match at line boundaries",
+			cfg:      config.Grep{Pattern: `^\}$`, Multiline: true, CountOnly: true},
+			expected: "2\n",
+		},
+		{
+			name:     "invert",
+			cfg:      config.Grep{Pattern: `\{\n\}`, Multiline: true, InvertMatch: true, CountOnly: true},
+			expected: "6\n",
+		},
+		{
+			name:     "without multiline the pattern never matches",
+			cfg:      config.Grep{Pattern: `\{\n\s*return`, CountOnly: true},
+			expected: "0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			output := &strings.Builder{}
+			if err := NewService(&cfg).Process(strings.NewReader(input), output); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package grep
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	errQueryMultiline = errors.New("boolean queries cannot be combined with multiline mode")
+	errQueryReplace   = errors.New("--expr, --and, --or and --not cannot be combined with --replace")
+)
+
+type predicate func(string) bool
+
+func (s *Service) hasQuery() bool {
+	return s.cfg.Expr != "" || len(s.cfg.And) > 0 || len(s.cfg.Or) > 0 || len(s.cfg.Not) > 0
+}
+
+// buildQueryMatcher combines the pattern with the --or, --and and --not
+// patterns as (pattern || or...) && and... && !not..., or compiles the
+// --expr expression instead. Every term honours -F and -i.
+func (s *Service) buildQueryMatcher() (func(string) bool, error) {
+	if s.cfg.Expr != "" {
+		return s.compileExpr(s.cfg.Expr)
+	}
+
+	main, err := s.buildPatternMatcher(s.cfg.Pattern)
+	if err != nil || !s.hasQuery() {
+		return main, err
+	}
+
+	ors, err := s.buildPatternMatchers(s.cfg.Or)
+	if err != nil {
+		return nil, err
+	}
+	ands, err := s.buildPatternMatchers(s.cfg.And)
+	if err != nil {
+		return nil, err
+	}
+	nots, err := s.buildPatternMatchers(s.cfg.Not)
+	if err != nil {
+		return nil, err
+	}
+	ors = append([]predicate{main}, ors...)
+
+	return func(line string) bool {
+		return anyMatch(ors, line) && allMatch(ands, line) && !anyMatch(nots, line)
+	}, nil
+}
+
+func (s *Service) buildPatternMatchers(patterns []string) ([]predicate, error) {
+	matchers := make([]predicate, 0, len(patterns))
+	for _, pattern := range patterns {
+		m, err := s.buildPatternMatcher(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func anyMatch(matchers []predicate, line string) bool {
+	for _, m := range matchers {
+		if m(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func allMatch(matchers []predicate, line string) bool {
+	for _, m := range matchers {
+		if !m(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileExpr parses expressions such as 'ERROR && !healthcheck' or
+// '(timeout || "connection reset") && !debug'. Terms are patterns, either
+// bare words or quoted with ' or ".
+//
+//	expr  = and { "||" and }
+//	and   = unary { "&&" unary }
+//	unary = "!" unary | "(" expr ")" | term
+func (s *Service) compileExpr(expr string) (func(string) bool, error) {
+	p := &exprParser{s: s, src: expr}
+
+	pred, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return pred, nil
+}
+
+type exprParser struct {
+	s   *Service
+	src string
+	pos int
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid expression at %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) consume(token string) bool {
+	p.skipSpaces()
+	if strings.HasPrefix(p.src[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(line string) bool { return l(line) || right(line) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(line string) bool { return l(line) && right(line) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (predicate, error) {
+	if p.consume("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(line string) bool { return !operand(line) }, nil
+	}
+
+	if p.consume("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing ')'")
+		}
+		return inner, nil
+	}
+
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	return p.s.buildPatternMatcher(term)
+}
+
+func (p *exprParser) parseTerm() (string, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return "", p.errorf("pattern expected")
+	}
+
+	if quote := p.src[p.pos]; quote == '\'' || quote == '"' {
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.errorf("unterminated %c", quote)
+		}
+		term := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return term, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if unicode.IsSpace(rune(c)) || c == '(' || c == ')' || c == '!' ||
+			strings.HasPrefix(p.src[p.pos:], "&&") || strings.HasPrefix(p.src[p.pos:], "||") {
+			break
+		}
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", p.errorf("pattern expected")
+	}
+	return p.src[start:p.pos], nil
+}
@@ -0,0 +1,101 @@
+package grep
+
+import (
+	"strings"
+	"testing"
+	"wb-tech-l2/12/go-grep/internal/config"
+)
+
+var queryLines = []string{
+	"INFO start",
+	"ERROR db timeout",
+	"ERROR healthcheck failed",
+	"WARN slow query",
+	"error: connection reset",
+	"INFO done",
+}
+
+func runQuery(t *testing.T, cfg *config.Grep) string {
+	t.Helper()
+	output := &strings.Builder{}
+	if err := NewService(cfg).Process(strings.NewReader(strings.Join(queryLines, "\n")), output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return output.String()
+}
+
+func TestService_Process_BooleanFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Grep
+		expected string
+	}{
+		{
+			name:     "and not",
+			cfg:      config.Grep{Pattern: "ERROR", Not: []string{"healthcheck"}},
+			expected: "ERROR db timeout\n",
+		},
+		{
+			name:     "or",
+			cfg:      config.Grep{Pattern: "WARN", Or: []string{"timeout"}, LineNumber: true},
+			expected: "2:ERROR db timeout\n4:WARN slow query\n",
+		},
+		{
+			name:     "and with ignore case",
+			cfg:      config.Grep{Pattern: "error", And: []string{"RESET"}, IgnoreCase: true},
+			expected: "error: connection reset\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if got := runQuery(t, &cfg); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_Process_Expr(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"ERROR && !healthcheck", "ERROR db timeout\n"},
+		{"(timeout || 'connection reset') && !INFO", "ERROR db timeout\nerror: connection reset\n"},
+		{`"slow query" || !!done`, "WARN slow query\nINFO done\n"},
+		{`^INFO && !start`, "INFO done\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := runQuery(t, &config.Grep{Expr: tt.expr}); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestService_Process_ExprInvertWithContext(t *testing.T) {
+	cfg := &config.Grep{
+		Expr:        "INFO || ERROR",
+		InvertMatch: true,
+		Context:     1,
+		LineNumber:  true,
+	}
+
+	expected := "3-ERROR healthcheck failed\n4:WARN slow query\n5:error: connection reset\n6-INFO done\n"
+	if got := runQuery(t, cfg); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestCompileExpr_Errors(t *testing.T) {
+	service := NewService(&config.Grep{})
+	for _, expr := range []string{"a &&", "(a || b", "a b", "'open", "!", "a && ("} {
+		if _, err := service.compileExpr(expr); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
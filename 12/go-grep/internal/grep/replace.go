@@ -0,0 +1,171 @@
+package grep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	errInvertedReplace = errors.New("replacement cannot be combined with invert match")
+	errInPlaceDir      = errors.New("--in-place needs a regular file, not a directory")
+)
+
+// buildReplacer returns nil when no replacement template is configured.
+// Otherwise every match in a line is substituted with the template, with
+// $1 and ${name} expanded from the match groups.
+func (s *Service) buildReplacer() (func(string) string, error) {
+	if !s.cfg.HasReplace || s.cfg.InvertMatch {
+		return nil, nil
+	}
+	if s.cfg.Format != "" {
+		return nil, errFormatReplace
+	}
+	// The template only knows the groups of the main pattern.
+	if s.hasQuery() {
+		return nil, errQueryReplace
+	}
+
+	re, err := s.compilePattern()
+	if err != nil {
+		return nil, err
+	}
+
+	template := s.cfg.Replace
+	return func(line string) string {
+		return expandAll(re, template, line)
+	}, nil
+}
+
+func expandAll(re *regexp.Regexp, template, line string) string {
+	matches := re.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	result := make([]byte, 0, len(line))
+	last := 0
+	for _, m := range matches {
+		result = append(result, line[last:m[0]]...)
+		result = re.ExpandString(result, template, line, m)
+		last = m[1]
+	}
+	return string(append(result, line[last:]...))
+}
+
+// Substitute applies the replacement template to the file at path. In dry
+// run mode a unified diff is written to w and the file is left untouched,
+// otherwise the file is replaced atomically, optionally keeping the
+// original as path.bak. A symlink stays in place and its target is
+// replaced.
+func (s *Service) Substitute(path string, w io.Writer) error {
+	if s.cfg.InvertMatch {
+		return errInvertedReplace
+	}
+	if s.cfg.Multiline {
+		return errMultilineReplace
+	}
+
+	replace, err := s.buildReplacer()
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if replace == nil {
+		return errors.New("replacement template is required")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errInPlaceDir
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	oldLines := splitLines(string(original))
+	newLines := make([]string, len(oldLines))
+	changed := false
+	for i, line := range oldLines {
+		body, ending := cutLineEnding(line)
+		newLines[i] = replace(body) + ending
+		if newLines[i] != line {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if s.cfg.DryRun {
+		return writeUnifiedDiff(w, path, oldLines, newLines)
+	}
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.Backup {
+		if err = os.WriteFile(path+".bak", original, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(target, []byte(strings.Join(newLines, "")), info.Mode().Perm())
+}
+
+// splitLines splits text into lines that keep their line endings.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func cutLineEnding(line string) (string, string) {
+	if strings.HasSuffix(line, "\r\n") {
+		return line[:len(line)-2], "\r\n"
+	}
+	if strings.HasSuffix(line, "\n") {
+		return line[:len(line)-1], "\n"
+	}
+	return line, ""
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
@@ -0,0 +1,126 @@
+package grep
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wb-tech-l2/12/go-grep/internal/config"
+)
+
+func TestService_Process_Replace(t *testing.T) {
+	cfg := &config.Grep{
+		Pattern:    `(?P<key>\w+)=(\d+)`,
+		Replace:    "${key}:[$2]",
+		HasReplace: true,
+		Context:    1,
+	}
+	service := NewService(cfg)
+
+	output := &strings.Builder{}
+	err := service.Process(strings.NewReader("head\nid=1 port=80\ntail=x\n"), output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "head\nid:[1] port:[80]\ntail=x\n"
+	if output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+}
+
+func TestService_Substitute(t *testing.T) {
+	content := "a\nb\nc\nd\nfoo 1\ne\nf\ng\nh\ni\nj\nk\nfoo 2"
+
+	newService := func(dryRun, backup bool) *Service {
+		return NewService(&config.Grep{
+			Pattern:    `foo (\d)`,
+			Replace:    "bar $1",
+			HasReplace: true,
+			DryRun:     dryRun,
+			Backup:     backup,
+		})
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		path := writeTempFile(t, content)
+
+		output := &strings.Builder{}
+		if err := newService(true, false).Substitute(path, output); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := "--- " + path + "\n+++ " + path + "\n" +
+			"@@ -2,7 +2,7 @@\n b\n c\n d\n-foo 1\n+bar 1\n e\n f\n g\n" +
+			"@@ -10,4 +10,4 @@\n i\n j\n k\n-foo 2\n\\ No newline at end of file\n+bar 2\n\\ No newline at end of file\n"
+		if output.String() != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, output.String())
+		}
+
+		data, _ := os.ReadFile(path)
+		if string(data) != content {
+			t.Error("Dry run must not modify the file")
+		}
+	})
+
+	t.Run("in place with backup", func(t *testing.T) {
+		path := writeTempFile(t, content)
+
+		if err := newService(false, true).Substitute(path, &strings.Builder{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		expected := strings.ReplaceAll(content, "foo", "bar")
+		if string(data) != expected {
+			t.Errorf("Expected %q, got %q", expected, data)
+		}
+
+		backup, err := os.ReadFile(path + ".bak")
+		if err != nil || string(backup) != content {
+			t.Errorf("Backup not written correctly: %v", err)
+		}
+	})
+
+	t.Run("through a symlink", func(t *testing.T) {
+		path := writeTempFile(t, content)
+		link := filepath.Join(t.TempDir(), "link.txt")
+		if err := os.Symlink(path, link); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := newService(false, false).Substitute(link, &strings.Builder{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("Expected %s to stay a symlink (%v)", link, err)
+		}
+		data, _ := os.ReadFile(path)
+		if expected := strings.ReplaceAll(content, "foo", "bar"); string(data) != expected {
+			t.Errorf("Expected the target to become %q, got %q", expected, data)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		if err := newService(false, false).Substitute(t.TempDir(), &strings.Builder{}); !errors.Is(err, errInPlaceDir) {
+			t.Errorf("Expected errInPlaceDir, got %v", err)
+		}
+	})
+}
+
+func TestService_Replace_RejectsQueries(t *testing.T) {
+	for _, cfg := range []config.Grep{
+		{Pattern: "a", Expr: "a && b"},
+		{Pattern: "a", And: []string{"b"}},
+		{Pattern: "a", Or: []string{"b"}},
+		{Pattern: "a", Not: []string{"b"}},
+	} {
+		cfg.Replace, cfg.HasReplace = "x", true
+		err := NewService(&cfg).Process(strings.NewReader("a b\n"), &strings.Builder{})
+		if !errors.Is(err, errQueryReplace) {
+			t.Errorf("Expected errQueryReplace for %+v, got %v", cfg, err)
+		}
+	}
+}
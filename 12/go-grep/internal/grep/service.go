@@ -19,24 +19,44 @@ func NewService(cfg *config.Grep) *Service {
 }
 
 func (s *Service) Process(r io.Reader, w io.Writer) error {
+	return s.process(r, w, "")
+}
+
+// ProcessNamed works like Process but prefixes every output line with
+// "name:", so results from several inputs can be told apart.
+func (s *Service) ProcessNamed(name string, r io.Reader, w io.Writer) error {
+	return s.process(r, w, name+":")
+}
+
+func (s *Service) process(r io.Reader, w io.Writer, prefix string) error {
 	lines, err := readLines(r)
 	if err != nil {
 		return fmt.Errorf("failed to read lines: %w", err)
 	}
 
-	matcher, err := s.buildMatcher()
+	matched, count, err := s.match(lines)
 	if err != nil {
-		return fmt.Errorf("invalid pattern: %w", err)
+		return err
 	}
 
-	matched, count := s.matchLines(lines, matcher)
-
 	if s.cfg.CountOnly {
-		return s.printCount(w, count)
+		return s.printCount(w, prefix, count)
+	}
+
+	replace, err := s.buildReplacer()
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if replace != nil {
+		for i := range lines {
+			if matched[i] {
+				lines[i] = replace(lines[i])
+			}
+		}
 	}
 
 	toPrint := s.applyContext(matched)
-	return s.printLines(w, lines, matched, toPrint)
+	return s.printLines(w, prefix, lines, matched, toPrint)
 }
 
 func readLines(r io.Reader) ([]string, error) {
@@ -48,25 +68,67 @@ func readLines(r io.Reader) ([]string, error) {
 	return lines, scanner.Err()
 }
 
-func (s *Service) buildMatcher() (func(string) bool, error) {
+func (s *Service) match(lines []string) ([]bool, int, error) {
+	if s.cfg.Multiline {
+		re, err := s.compileMultiline()
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid pattern: %w", err)
+		}
+		matched, count := s.matchMultiline(lines, re)
+		return matched, count, nil
+	}
+
+	matcher, header, err := s.buildMatcher()
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	if header == nil || len(lines) == 0 {
+		matched, count := s.matchLines(lines, matcher)
+		return matched, count, nil
+	}
+
+	// The header is neither matched nor counted, even with -v.
+	if err = header(lines[0]); err != nil {
+		return nil, 0, err
+	}
+	matched, count := s.matchLines(lines[1:], matcher)
+	return append([]bool{false}, matched...), count, nil
+}
+
+// buildMatcher returns the line matcher and, for input that starts with a
+// header, the function that has to read the header first.
+func (s *Service) buildMatcher() (func(string) bool, func(string) error, error) {
+	extract, header, err := s.fieldExtractor()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	matcher, err := s.buildQueryMatcher()
+	if err != nil || extract == nil {
+		return matcher, nil, err
+	}
+
+	return func(line string) bool {
+		value, ok := extract(line)
+		return ok && matcher(value)
+	}, header, nil
+}
+
+func (s *Service) buildPatternMatcher(pattern string) (func(string) bool, error) {
 	if s.cfg.FixedString {
 		if s.cfg.IgnoreCase {
-			pattern := strings.ToLower(s.cfg.Pattern)
+			lower := strings.ToLower(pattern)
 			return func(line string) bool {
-				return strings.Contains(strings.ToLower(line), pattern)
+				return strings.Contains(strings.ToLower(line), lower)
 			}, nil
 		}
 		return func(line string) bool {
-			return strings.Contains(line, s.cfg.Pattern)
+			return strings.Contains(line, pattern)
 		}, nil
 	}
 
-	pattern := s.cfg.Pattern
-	if s.cfg.IgnoreCase {
-		pattern = "(?i)" + pattern
-	}
-
-	re, err := regexp.Compile(pattern)
+	re, err := s.compileRegexp(pattern)
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +138,21 @@ func (s *Service) buildMatcher() (func(string) bool, error) {
 	}, nil
 }
 
+func (s *Service) compilePattern() (*regexp.Regexp, error) {
+	return s.compileRegexp(s.cfg.Pattern)
+}
+
+func (s *Service) compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if s.cfg.FixedString {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if s.cfg.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
 func (s *Service) matchLines(lines []string, matcher func(string) bool) ([]bool, int) {
 	matched := make([]bool, len(lines))
 	count := 0
@@ -98,13 +175,7 @@ func (s *Service) applyContext(matched []bool) []bool {
 	n := len(matched)
 	toPrint := make([]bool, n)
 
-	after := s.cfg.AfterContext
-	before := s.cfg.BeforeContext
-
-	if s.cfg.Context > 0 {
-		after = s.cfg.Context
-		before = s.cfg.Context
-	}
+	before, after := s.contextSize()
 
 	for i := 0; i < n; i++ {
 		if !matched[i] {
@@ -127,35 +198,47 @@ func (s *Service) applyContext(matched []bool) []bool {
 	return toPrint
 }
 
-func (s *Service) printCount(w io.Writer, count int) error {
-	_, err := fmt.Fprintf(w, "%d\n", count)
+func (s *Service) contextSize() (before, after int) {
+	if s.cfg.Context > 0 {
+		return s.cfg.Context, s.cfg.Context
+	}
+	return s.cfg.BeforeContext, s.cfg.AfterContext
+}
+
+func (s *Service) printCount(w io.Writer, prefix string, count int) error {
+	_, err := fmt.Fprintf(w, "%s%d\n", prefix, count)
 	return err
 }
 
-func (s *Service) printLines(w io.Writer, lines []string, matched, toPrint []bool) error {
+func (s *Service) printLines(w io.Writer, prefix string, lines []string, matched, toPrint []bool) error {
 	for i := 0; i < len(lines); i++ {
 		if !toPrint[i] {
 			continue
 		}
 
-		var output string
-		if s.cfg.LineNumber {
-			prefix := fmt.Sprintf("%d:", i+1)
-			if !matched[i] {
-				prefix = fmt.Sprintf("%d-", i+1)
-			}
-			output = prefix + lines[i] + "\n"
-		} else {
-			output = lines[i] + "\n"
-		}
-
-		if _, err := w.Write([]byte(output)); err != nil {
+		if err := s.printLine(w, prefix, i+1, lines[i], matched[i]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (s *Service) printLine(w io.Writer, prefix string, num int, line string, matched bool) error {
+	var output string
+	if s.cfg.LineNumber {
+		lineNum := fmt.Sprintf("%d:", num)
+		if !matched {
+			lineNum = fmt.Sprintf("%d-", num)
+		}
+		output = prefix + lineNum + line + "\n"
+	} else {
+		output = prefix + line + "\n"
+	}
+
+	_, err := w.Write([]byte(output))
+	return err
+}
+
 func (s *Service) ProcessLines(lines []string) []string {
 	buffer := strings.NewReader(strings.Join(lines, "\n"))
 	output := &strings.Builder{}
@@ -190,7 +190,7 @@ func TestBuildMatcher_FixedStringCaseSensitive(t *testing.T) {
 	}
 	service := NewService(cfg)
 
-	matcher, err := service.buildMatcher()
+	matcher, _, err := service.buildMatcher()
 	if err != nil {
 		t.Fatalf("Failed to build matcher: %v", err)
 	}
@@ -211,7 +211,7 @@ func TestBuildMatcher_FixedStringIgnoreCase(t *testing.T) {
 	}
 	service := NewService(cfg)
 
-	matcher, err := service.buildMatcher()
+	matcher, _, err := service.buildMatcher()
 	if err != nil {
 		t.Fatalf("Failed to build matcher: %v", err)
 	}
@@ -246,3 +246,22 @@ func TestMatchLines_Invert(t *testing.T) {
 		t.Error("Inverted match incorrectly matched hello lines")
 	}
 }
+
+func TestService_ProcessNamed(t *testing.T) {
+	cfg := &config.Grep{
+		Pattern:    "hello",
+		LineNumber: true,
+	}
+	service := NewService(cfg)
+
+	output := &strings.Builder{}
+	err := service.ProcessNamed("logs.tar.gz:app.log", strings.NewReader("first\nhello world\n"), output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "logs.tar.gz:app.log:2:hello world\n"
+	if output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+}
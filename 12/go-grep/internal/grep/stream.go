@@ -0,0 +1,104 @@
+package grep
+
+import "io"
+
+type bufferedLine struct {
+	num  int
+	text string
+}
+
+// lineStream applies matching and context rules to lines as they arrive,
+// keeping only the last before-context lines in memory.
+type lineStream struct {
+	s       *Service
+	w       io.Writer
+	prefix  string
+	matcher func(string) bool
+	replace func(string) string
+
+	header     func(string) error // reads the first line, when set
+	headerRead bool
+
+	before    int
+	after     int
+	buffered  []bufferedLine
+	afterLeft int
+
+	lineNum int
+	count   int
+}
+
+func (s *Service) newLineStream(w io.Writer, prefix string, matcher func(string) bool) *lineStream {
+	before, after := s.contextSize()
+	return &lineStream{
+		s:        s,
+		w:        w,
+		prefix:   prefix,
+		matcher:  matcher,
+		before:   before,
+		after:    after,
+		buffered: make([]bufferedLine, 0, before),
+	}
+}
+
+func (ls *lineStream) feed(line string) error {
+	ls.lineNum++
+
+	matches := false
+	if ls.header != nil && !ls.headerRead {
+		// The header is neither matched nor counted, even with -v.
+		ls.headerRead = true
+		if err := ls.header(line); err != nil {
+			return err
+		}
+	} else {
+		matches = ls.matcher(line)
+		if ls.s.cfg.InvertMatch {
+			matches = !matches
+		}
+	}
+
+	if matches {
+		ls.count++
+	}
+	if ls.s.cfg.CountOnly {
+		return nil
+	}
+
+	if matches {
+		for _, b := range ls.buffered {
+			if err := ls.s.printLine(ls.w, ls.prefix, b.num, b.text, false); err != nil {
+				return err
+			}
+		}
+		ls.buffered = ls.buffered[:0]
+		ls.afterLeft = ls.after
+		if ls.replace != nil {
+			line = ls.replace(line)
+		}
+		return ls.s.printLine(ls.w, ls.prefix, ls.lineNum, line, true)
+	}
+
+	if ls.afterLeft > 0 {
+		ls.afterLeft--
+		return ls.s.printLine(ls.w, ls.prefix, ls.lineNum, line, false)
+	}
+
+	if ls.before > 0 {
+		if len(ls.buffered) == ls.before {
+			copy(ls.buffered, ls.buffered[1:])
+			ls.buffered = ls.buffered[:ls.before-1]
+		}
+		ls.buffered = append(ls.buffered, bufferedLine{num: ls.lineNum, text: line})
+	}
+	return nil
+}
+
+// reset starts numbering, context and the header from scratch, e.g. after
+// the followed file was truncated or rotated. The match count is kept.
+func (ls *lineStream) reset() {
+	ls.lineNum = 0
+	ls.headerRead = false
+	ls.afterLeft = 0
+	ls.buffered = ls.buffered[:0]
+}
@@ -0,0 +1,72 @@
+package grep
+
+import (
+	"strings"
+	"testing"
+	"wb-tech-l2/12/go-grep/internal/config"
+)
+
+func TestLineStream_MatchesProcess(t *testing.T) {
+	lines := []string{"a", "b", "hello 1", "c", "d", "e", "hello 2", "hello 3", "f", "g"}
+
+	tests := []struct {
+		name string
+		cfg  config.Grep
+	}{
+		{"plain", config.Grep{Pattern: "hello"}},
+		{"line numbers", config.Grep{Pattern: "hello", LineNumber: true}},
+		{"context", config.Grep{Pattern: "hello", Context: 1, LineNumber: true}},
+		{"before and after", config.Grep{Pattern: "hello", BeforeContext: 2, AfterContext: 1, LineNumber: true}},
+		{"invert with context", config.Grep{Pattern: "hello", InvertMatch: true, Context: 1, LineNumber: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			service := NewService(&cfg)
+
+			expected := &strings.Builder{}
+			if err := service.Process(strings.NewReader(strings.Join(lines, "\n")), expected); err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+
+			matcher, _, err := service.buildMatcher()
+			if err != nil {
+				t.Fatalf("Failed to build matcher: %v", err)
+			}
+
+			got := &strings.Builder{}
+			stream := service.newLineStream(got, "", matcher)
+			for _, line := range lines {
+				if err = stream.feed(line); err != nil {
+					t.Fatalf("feed failed: %v", err)
+				}
+			}
+
+			if got.String() != expected.String() {
+				t.Errorf("Expected:\n%q\nGot:\n%q", expected.String(), got.String())
+			}
+		})
+	}
+}
+
+func TestLineStream_Reset(t *testing.T) {
+	cfg := &config.Grep{Pattern: "x", LineNumber: true, CountOnly: false}
+	service := NewService(cfg)
+	matcher, _, _ := service.buildMatcher()
+
+	got := &strings.Builder{}
+	stream := service.newLineStream(got, "", matcher)
+	_ = stream.feed("a")
+	_ = stream.feed("x1")
+	stream.reset()
+	_ = stream.feed("x2")
+
+	expected := "2:x1\n1:x2\n"
+	if got.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, got.String())
+	}
+	if stream.count != 2 {
+		t.Errorf("Expected count to survive reset, got %d", stream.count)
+	}
+}
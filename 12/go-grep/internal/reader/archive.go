@@ -0,0 +1,159 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+var zipMagic = []byte("PK\x03\x04")
+
+// fileReaderAt is an input, such as an *os.File, that a zip archive can
+// be read from in place rather than loaded into memory first.
+type fileReaderAt interface {
+	io.ReaderAt
+	Stat() (fs.FileInfo, error)
+}
+
+const (
+	tarBlockSize   = 512
+	tarMagicOffset = 257
+)
+
+// MemberFunc is called for every regular file found in an archive.
+// An empty member name means the input was not an archive.
+type MemberFunc func(member string, r io.Reader) error
+
+// WalkArchive calls fn for every regular file stored in a .tar or .zip
+// archive. When decompress is set, a compressed tar (.tar.gz, .tar.xz,
+// ...) is read too, and compressed members are decompressed before being
+// passed to fn. Input that is not an archive is passed to fn as a whole,
+// and as it is unless decompress is set.
+func WalkArchive(r io.Reader, decompress bool, fn MemberFunc) error {
+	const op = "reader.WalkArchive"
+
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(zipMagic))
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if bytes.Equal(head, zipMagic) {
+		if err = walkZip(r, br, decompress, fn); err != nil {
+			return fmt.Errorf("%s: zip: %w", op, err)
+		}
+		return nil
+	}
+
+	stream := io.NopCloser(br)
+	if decompress {
+		if stream, err = Decompress(br); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	defer func() { _ = stream.Close() }()
+
+	sr := bufio.NewReaderSize(stream, tarBlockSize)
+	block, err := sr.Peek(tarBlockSize)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !isTar(block) {
+		return fn("", sr)
+	}
+
+	if err = walkTar(sr, decompress, fn); err != nil {
+		return fmt.Errorf("%s: tar: %w", op, err)
+	}
+	return nil
+}
+
+func isTar(block []byte) bool {
+	if len(block) < tarBlockSize {
+		return false
+	}
+	return bytes.HasPrefix(block[tarMagicOffset:], []byte("ustar"))
+}
+
+func walkTar(r io.Reader, decompress bool, fn MemberFunc) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err = visitMember(hdr.Name, tr, decompress, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// walkZip reads the archive through ReadAt when r is a regular file, and
+// otherwise from br into memory, since zip keeps its index at the end.
+func walkZip(r io.Reader, br *bufio.Reader, decompress bool, fn MemberFunc) error {
+	var ra io.ReaderAt
+	var size int64
+	if f, ok := r.(fileReaderAt); ok {
+		if info, err := f.Stat(); err == nil && info.Mode().IsRegular() {
+			ra, size = f, info.Size()
+		}
+	}
+	if ra == nil {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return err
+		}
+		ra, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		err = visitMember(f.Name, rc, decompress, fn)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitMember(name string, r io.Reader, decompress bool, fn MemberFunc) error {
+	if !decompress {
+		return fn(name, r)
+	}
+
+	dr, err := Decompress(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer func() { _ = dr.Close() }()
+
+	return fn(name, dr)
+}
@@ -0,0 +1,208 @@
+package reader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func collect(t *testing.T, input []byte, decompress bool) map[string]string {
+	t.Helper()
+	members := make(map[string]string)
+	err := WalkArchive(bytes.NewReader(input), decompress, func(member string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		members[member] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkArchive: %v", err)
+	}
+	return members
+}
+
+// bzip2Text is "hello\nworld\n" compressed with bzip2 -9, for which Go
+// has no encoder.
+var bzip2Text = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x6b, 0x5f,
+	0xb1, 0xdd, 0x00, 0x00, 0x02, 0x41, 0x80, 0x00, 0x10, 0x06, 0x44, 0x90,
+	0x80, 0x20, 0x00, 0x31, 0x0c, 0x08, 0x21, 0xa3, 0x69, 0x08, 0x07, 0x23,
+	0xae, 0x87, 0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48, 0x35, 0xaf, 0xd8, 0xee,
+	0x80,
+}
+
+func TestDecompress(t *testing.T) {
+	const text = "hello\nworld\n"
+
+	var zstdBuf bytes.Buffer
+	zw, _ := zstd.NewWriter(&zstdBuf)
+	_, _ = zw.Write([]byte(text))
+	_ = zw.Close()
+
+	var xzBuf bytes.Buffer
+	xw, _ := xz.NewWriter(&xzBuf)
+	_, _ = xw.Write([]byte(text))
+	_ = xw.Close()
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"plain", []byte(text)},
+		{"gzip", gzipBytes(t, text)},
+		{"bzip2", bzip2Text},
+		{"zstd", zstdBuf.Bytes()},
+		{"xz", xzBuf.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Decompress(bytes.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Decompress: %v", err)
+			}
+			defer func() { _ = r.Close() }()
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if string(got) != text {
+				t.Errorf("Expected %q, got %q", text, got)
+			}
+		})
+	}
+}
+
+func TestDecompress_BZhText(t *testing.T) {
+	for _, text := range []string{"BZh", "BZh9 is plain text\n", "BZh91AY&SX not bzip2\n"} {
+		r, err := Decompress(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("Decompress(%q): %v", text, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || string(got) != text {
+			t.Errorf("Expected %q unchanged, got %q (%v)", text, got, err)
+		}
+	}
+}
+
+func TestWalkArchive_TarGz(t *testing.T) {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	files := map[string][]byte{
+		"logs/a.txt":    []byte("alpha\n"),
+		"logs/b.txt.gz": gzipBytes(t, "beta\n"),
+	}
+	for name, data := range files {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write(data)
+	}
+	_ = tw.Close()
+
+	members := collect(t, gzipBytes(t, tarBuf.String()), true)
+
+	if members["logs/a.txt"] != "alpha\n" {
+		t.Errorf("Unexpected logs/a.txt content: %q", members["logs/a.txt"])
+	}
+	if members["logs/b.txt.gz"] != "beta\n" {
+		t.Errorf("Compressed member was not decompressed: %q", members["logs/b.txt.gz"])
+	}
+}
+
+func TestWalkArchive_CompressedWithoutDecompress(t *testing.T) {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	_ = tw.WriteHeader(&tar.Header{Name: "a.txt", Mode: 0o644, Size: 6, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("alpha\n"))
+	_ = tw.Close()
+
+	for name, input := range map[string][]byte{
+		"tar.gz": gzipBytes(t, tarBuf.String()),
+		"gz":     gzipBytes(t, "alpha\n"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			members := collect(t, input, false)
+			if len(members) != 1 || members[""] != string(input) {
+				t.Errorf("Expected the raw bytes as a whole, got %v", members)
+			}
+		})
+	}
+}
+
+func TestWalkArchive_Zip(t *testing.T) {
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	f, _ := zw.Create("inner/path.txt")
+	_, _ = f.Write([]byte("zipped\n"))
+	_ = zw.Close()
+
+	members := collect(t, zipBuf.Bytes(), false)
+
+	if len(members) != 1 || members["inner/path.txt"] != "zipped\n" {
+		t.Errorf("Unexpected zip members: %v", members)
+	}
+}
+
+func TestWalkArchive_ZipFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(file)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		f, _ := zw.Create(name)
+		_, _ = f.Write([]byte(name + "\n"))
+	}
+	_ = zw.Close()
+	_ = file.Close()
+
+	file, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+
+	members := make(map[string]string)
+	err = WalkArchive(file, false, func(member string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		members[member] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkArchive: %v", err)
+	}
+	if len(members) != 2 || members["a.txt"] != "a.txt\n" || members["b.txt"] != "b.txt\n" {
+		t.Errorf("Unexpected zip members: %v", members)
+	}
+}
+
+func TestWalkArchive_NotAnArchive(t *testing.T) {
+	members := collect(t, []byte("just text\n"), false)
+
+	if len(members) != 1 || members[""] != "just text\n" {
+		t.Errorf("Expected plain input passed as a whole, got %v", members)
+	}
+}
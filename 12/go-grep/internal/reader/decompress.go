@@ -0,0 +1,75 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+var (
+	// A bzip2 stream goes on with the block size digit and the magic of
+	// its first block, or of the end of the stream when it is empty.
+	bzip2BlockMagic = []byte("1AY&SY")
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+const magicLen = 10
+
+// Decompress detects gzip, bzip2, zstd and xz streams by their magic bytes
+// and returns a reader over the decompressed data. Any other input is
+// returned unchanged.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	const op = "reader.Decompress"
+
+	br := bufio.NewReader(r)
+	head, err := br.Peek(magicLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: gzip: %w", op, err)
+		}
+		return zr, nil
+	case isBzip2(head):
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: zstd: %w", op, err)
+		}
+		return zr.IOReadCloser(), nil
+	case bytes.HasPrefix(head, xzMagic):
+		zr, err := xz.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: xz: %w", op, err)
+		}
+		return io.NopCloser(zr), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// isBzip2 checks more than the "BZh" prefix, which plain text may have.
+func isBzip2(head []byte) bool {
+	if len(head) < magicLen || !bytes.HasPrefix(head, bzip2Magic) || head[3] < '1' || head[3] > '9' {
+		return false
+	}
+	return bytes.Equal(head[4:], bzip2BlockMagic) || bytes.Equal(head[4:], bzip2EndMagic)
+}
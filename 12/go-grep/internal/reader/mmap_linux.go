@@ -0,0 +1,33 @@
+//go:build linux
+
+package reader
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// MapFile maps the whole file into memory read-only. The returned release
+// function must be called once the data is no longer used.
+func MapFile(file *os.File) ([]byte, func() error, error) {
+	const op = "reader.MapFile"
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_ = syscall.Madvise(data, syscall.MADV_SEQUENTIAL)
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
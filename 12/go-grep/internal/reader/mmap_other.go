@@ -0,0 +1,22 @@
+//go:build !linux
+
+package reader
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// MapFile reads the whole file into memory. On Linux the file is
+// memory-mapped instead.
+func MapFile(file *os.File) ([]byte, func() error, error) {
+	const op = "reader.MapFile"
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return data, func() error { return nil }, nil
+}
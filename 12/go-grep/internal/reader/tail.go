@@ -0,0 +1,155 @@
+package reader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Tailer reads a file line by line and keeps reading as it grows, like
+// tail -F. Truncation and rotation (the path pointing to a new inode) are
+// detected on every poll and make the Tailer start over from the beginning.
+type Tailer struct {
+	path     string
+	interval time.Duration
+
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64
+	partial strings.Builder
+}
+
+func NewTailer(path string, interval time.Duration) *Tailer {
+	return &Tailer{path: path, interval: interval}
+}
+
+// Run calls onLine for every complete line and onReset whenever the file
+// is read again from the start. It returns when ctx is done, after passing
+// an unterminated last line to onLine.
+func (t *Tailer) Run(ctx context.Context, onLine func(string) error, onReset func()) error {
+	const op = "reader.Tailer.Run"
+
+	if err := t.open(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = t.file.Close() }()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.drain(onLine); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			if t.partial.Len() > 0 {
+				return onLine(t.takePartial())
+			}
+			return nil
+		case <-ticker.C:
+		}
+
+		reopened, err := t.checkRotation(onLine)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if reopened {
+			onReset()
+		}
+	}
+}
+
+func (t *Tailer) open() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+
+	if t.file != nil {
+		_ = t.file.Close()
+	}
+	t.file = file
+	t.reader = bufio.NewReader(file)
+	t.offset = 0
+	t.partial.Reset()
+	return nil
+}
+
+// drain reads everything currently available. A trailing line without a
+// newline is kept until the rest of it is written.
+func (t *Tailer) drain(onLine func(string) error) error {
+	for {
+		chunk, err := t.reader.ReadString('\n')
+		t.offset += int64(len(chunk))
+
+		if errors.Is(err, io.EOF) {
+			t.partial.WriteString(chunk)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		t.partial.WriteString(chunk)
+		if err = onLine(t.takePartial()); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *Tailer) takePartial() string {
+	line := t.partial.String()
+	t.partial.Reset()
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+func (t *Tailer) checkRotation(onLine func(string) error) (bool, error) {
+	current, err := t.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if current.Size() < t.offset {
+		if _, err = t.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		t.reader.Reset(t.file)
+		t.offset = 0
+		t.partial.Reset()
+		return true, nil
+	}
+
+	latest, err := os.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil // rotated away, the new file is not created yet
+	}
+	if err != nil {
+		return false, err
+	}
+	if os.SameFile(current, latest) {
+		return false, nil
+	}
+
+	// Lines written to the old file before the rotation still belong to it.
+	if err = t.drain(onLine); err != nil {
+		return false, err
+	}
+	if t.partial.Len() > 0 {
+		if err = onLine(t.takePartial()); err != nil {
+			return false, err
+		}
+	}
+
+	if err = t.open(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
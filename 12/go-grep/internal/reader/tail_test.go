@@ -0,0 +1,99 @@
+package reader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const tailTimeout = 2 * time.Second
+
+type tailEvent struct {
+	line  string
+	reset bool
+}
+
+func startTailer(t *testing.T, path string) (<-chan tailEvent, context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	events := make(chan tailEvent, 16)
+	done := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		done <- NewTailer(path, 10*time.Millisecond).Run(ctx,
+			func(line string) error {
+				events <- tailEvent{line: line}
+				return nil
+			},
+			func() { events <- tailEvent{reset: true} },
+		)
+	}()
+
+	return events, cancel, done
+}
+
+func expectEvent(t *testing.T, events <-chan tailEvent, expected tailEvent) {
+	t.Helper()
+	select {
+	case got := <-events:
+		if got != expected {
+			t.Fatalf("Expected %+v, got %+v", expected, got)
+		}
+	case <-time.After(tailTimeout):
+		t.Fatalf("Timed out waiting for %+v", expected)
+	}
+}
+
+func appendTo(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTailer_AppendTruncateRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendTo(t, path, "one\n")
+
+	events, cancel, done := startTailer(t, path)
+	defer cancel()
+
+	expectEvent(t, events, tailEvent{line: "one"})
+
+	appendTo(t, path, "tw")
+	appendTo(t, path, "o\r\n")
+	expectEvent(t, events, tailEvent{line: "two"})
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events, tailEvent{reset: true})
+	appendTo(t, path, "after truncate\n")
+	expectEvent(t, events, tailEvent{line: "after truncate"})
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendTo(t, path+".1", "late write\n")
+	appendTo(t, path, "rotated\n")
+	expectEvent(t, events, tailEvent{line: "late write"})
+	expectEvent(t, events, tailEvent{reset: true})
+	expectEvent(t, events, tailEvent{line: "rotated"})
+
+	appendTo(t, path, "unterminated")
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	expectEvent(t, events, tailEvent{line: "unterminated"})
+
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+}
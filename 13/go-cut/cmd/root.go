@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -21,10 +22,21 @@ Examples:
   echo "a:b:c:d" | go-cut -d ":" -f 1,3
   echo "a:b:c:d" | go-cut -d ":" -f 2-4
   echo "a:b:c:d" | go-cut -d ":" -f 1,3-4 -s
+  echo "a:b:c:d" | go-cut -d ":" -f 3-
+  echo "a:b:c:d" | go-cut -d ":" -f 3,1 --keep-order
+  echo "a:b:c:d" | go-cut -d ":" -f 1,3 --output-delimiter ","
+  ps aux | go-cut --whitespace -f 2,11
+  go-cut --csv -f name,email users.csv
+  go-cut --jsonl -f .id,.user.name events.jsonl
+  go-cut --jsonl -f .id,.user.name --to tsv --missing - events.jsonl
+  echo "привет" | go-cut -c 1-3
+  echo "привет" | go-cut -b 1-3 -n
+  go-cut -d ":" -f 1 /etc/passwd /etc/group
+  find . -print0 | go-cut -z -d "/" -f 2
 `
 	appConfig = &config.Cut{}
 	rootCmd   = &cobra.Command{
-		Use:   appName,
+		Use:   appName + " [FILE]...",
 		Short: shortMsg,
 		Long:  longMsg,
 		Run:   runApp,
@@ -35,8 +47,21 @@ func init() {
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "shows app usage")
 
 	rootCmd.Flags().StringVarP(&appConfig.Fields, "fields", "f", "", "select only these fields")
+	rootCmd.Flags().StringVarP(&appConfig.Characters, "characters", "c", "", "select only these characters")
+	rootCmd.Flags().StringVarP(&appConfig.Bytes, "bytes", "b", "", "select only these bytes")
+	rootCmd.Flags().BoolVarP(&appConfig.NoSplit, "no-split", "n", false, "with -b, do not split multibyte characters")
+	rootCmd.Flags().BoolVarP(&appConfig.Complement, "complement", "", false, "select everything except the listed fields, characters or bytes")
+	rootCmd.Flags().BoolVarP(&appConfig.KeepOrder, "keep-order", "", false, "print in the listed order, repeating duplicates")
 	rootCmd.Flags().StringVarP(&appConfig.Delimiter, "delimiter", "d", "\t", "use DELIM instead of TAB for field delimiter")
+	rootCmd.Flags().StringVarP(&appConfig.RegexDelimiter, "regex-delimiter", "", "", "split fields on matches of REGEX instead of DELIM")
+	rootCmd.Flags().BoolVarP(&appConfig.Whitespace, "whitespace", "w", false, "split fields on runs of blanks, ignoring leading and trailing ones")
+	rootCmd.Flags().StringVarP(&appConfig.OutputDelimiter, "output-delimiter", "", "", "join output fields with STR (default: DELIM, or a space with --regex-delimiter and --whitespace)")
+	rootCmd.Flags().BoolVarP(&appConfig.CSV, "csv", "", false, "parse input as CSV (comma unless -d is given); -f may name header columns")
+	rootCmd.Flags().BoolVarP(&appConfig.JSONL, "jsonl", "", false, "parse input as JSON Lines; -f takes JSON paths like .id,.user.name")
+	rootCmd.Flags().StringVarP(&appConfig.To, "to", "", "", "with --jsonl, output json (default) or tsv")
+	rootCmd.Flags().StringVarP(&appConfig.Missing, "missing", "", "", "with --jsonl, placeholder for missing keys")
 	rootCmd.Flags().BoolVarP(&appConfig.SeparatedOnly, "separated", "s", false, "do not print lines not containing delimiters")
+	rootCmd.Flags().BoolVarP(&appConfig.ZeroTerminated, "zero-terminated", "z", false, "line delimiter is NUL, not newline")
 }
 
 func exitWithErrorMessage(message string) {
@@ -44,24 +69,55 @@ func exitWithErrorMessage(message string) {
 	os.Exit(1)
 }
 
-func runApp(_ *cobra.Command, args []string) {
+func runApp(cmd *cobra.Command, args []string) {
+	if appConfig.CSV && !cmd.Flags().Changed("delimiter") {
+		appConfig.Delimiter = ","
+	}
+
+	processor, err := cut.NewProcessor(appConfig)
+	if err != nil {
+		exitWithErrorMessage(fmt.Sprintf("invalid options: %s", err))
+	}
+	processor.OnRecordError = func(err error) {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	}
+
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	// Like GNU cut, a bad file is reported and skipped, and the exit code
+	// tells that something went wrong. Bad records have been reported as
+	// they came.
+	failed := false
+	for _, name := range args {
+		if err = processFile(processor, name); err != nil {
+			var recordErrs *cut.RecordsError
+			if !errors.As(err, &recordErrs) {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			}
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// processFile cuts the named file, or standard input for "-".
+func processFile(processor *cut.Processor, name string) error {
 	var input io.Reader = os.Stdin
-	if len(args) > 0 {
-		file, err := os.Open(args[0])
+	if name != "-" {
+		file, err := os.Open(name)
 		if err != nil {
-			exitWithErrorMessage(fmt.Sprintf("cannot open file: %s", err))
+			return err
 		}
 		defer func() { _ = file.Close() }()
 		input = file
 	}
 
-	if err := cut.Process(input, os.Stdout, cut.Opts{
-		Fields:        appConfig.Fields,
-		Delimiter:     appConfig.Delimiter,
-		SeparatedOnly: appConfig.SeparatedOnly,
-	}); err != nil {
-		exitWithErrorMessage(fmt.Sprintf("processing error: %s", err))
-	}
+	return processor.Process(input, os.Stdout)
 }
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
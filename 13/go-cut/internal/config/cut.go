@@ -1,7 +1,20 @@
 package config
 
 type Cut struct {
-	Fields        string
-	Delimiter     string
-	SeparatedOnly bool
+	Fields          string
+	Characters      string
+	Bytes           string
+	Delimiter       string
+	RegexDelimiter  string
+	Whitespace      bool
+	OutputDelimiter string
+	SeparatedOnly   bool
+	NoSplit         bool
+	Complement      bool
+	KeepOrder       bool
+	CSV             bool
+	ZeroTerminated  bool
+	JSONL           bool
+	To              string
+	Missing         string
 }
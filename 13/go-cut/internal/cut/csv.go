@@ -0,0 +1,106 @@
+package cut
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvDelimiter turns a -d or --output-delimiter value into the single
+// character encoding/csv works with.
+func csvDelimiter(delimiter string, fallback rune) (rune, error) {
+	if delimiter == "" {
+		return fallback, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) {
+		return 0, fmt.Errorf("csv delimiter must be a single character: %q", delimiter)
+	}
+	return r, nil
+}
+
+// resolveCSVList parses a field list that may name columns of the header
+// record, e.g. "name,email" or "id,3-". Numbers and ranges are parsed as
+// usual.
+func resolveCSVList(list string, header []string) ([]span, error) {
+	var result []span
+
+	for _, tok := range splitList(list) {
+		if s, ok, err := parseSpan(list, tok); err == nil {
+			if ok {
+				result = append(result, s)
+			}
+			continue
+		}
+
+		column := -1
+		for i, name := range header {
+			if strings.TrimSpace(name) == tok.text {
+				column = i + 1
+				break
+			}
+		}
+		if column < 0 {
+			return nil, &ParseError{List: list, Pos: tok.pos, Token: tok.text, Msg: "unknown column"}
+		}
+		result = append(result, span{lo: column, hi: column})
+	}
+
+	return result, nil
+}
+
+// processCSV cuts RFC 4180 records, so quoted delimiters and newlines stay
+// inside their fields, and writes the selected fields back as CSV.
+func processCSV(r io.Reader, w io.Writer, opts validOpts) error {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.csvComma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.csvOutComma
+
+	sel := opts.sel
+	first := true
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if first && opts.csvNames {
+			spans, err := resolveCSVList(opts.csvList, record)
+			if err != nil {
+				return err
+			}
+			sel = newSelection(spans, sel.complement, sel.keepOrder)
+		}
+		first = false
+
+		if len(record) < 2 {
+			if opts.separatedOnly {
+				continue
+			}
+		} else if !sel.empty() {
+			record = selectFields(record, sel)
+			if len(record) == 0 {
+				continue
+			}
+		}
+
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,11 +1,96 @@
 package cut
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"io"
 	"strings"
 	"testing"
+	"wb-tech-l2/13/go-cut/internal/config"
 )
 
+// The tests below form a conformance suite: every case runs through each
+// engine that can handle its options.
+
+type engine struct {
+	name    string
+	run     func(r io.Reader, w io.Writer, opts Opts) error
+	handles func(opts Opts) bool
+}
+
+// engines are the ways through the package that every conformance case
+// must agree on. Process picks the byte-level field cutter for a literal
+// delimiter and the CSV reader for --csv. The line engine, built from
+// config.Cut, cuts every record with cutLine instead; CSV input has no
+// such second engine.
+var engines = []engine{
+	{
+		name: "Process",
+		run: func(r io.Reader, w io.Writer, opts Opts) error {
+			return Process(r, w, opts)
+		},
+		handles: func(Opts) bool { return true },
+	},
+	{
+		name: "line engine",
+		run: func(r io.Reader, w io.Writer, opts Opts) error {
+			p, err := NewProcessor(&config.Cut{
+				Fields:          opts.Fields,
+				Characters:      opts.Characters,
+				Bytes:           opts.Bytes,
+				Delimiter:       opts.Delimiter,
+				RegexDelimiter:  opts.RegexDelimiter,
+				Whitespace:      opts.Whitespace,
+				OutputDelimiter: opts.OutputDelimiter,
+				SeparatedOnly:   opts.SeparatedOnly,
+				NoSplit:         opts.NoSplit,
+				Complement:      opts.Complement,
+				KeepOrder:       opts.KeepOrder,
+				CSV:             opts.CSV,
+				ZeroTerminated:  opts.ZeroTerminated,
+				JSONL:           opts.JSONL,
+				To:              opts.To,
+				Missing:         opts.Missing,
+			})
+			if err != nil {
+				return err
+			}
+			return p.processRecords(bufio.NewReader(r), bufio.NewWriter(w))
+		},
+		handles: func(opts Opts) bool { return !opts.CSV },
+	},
+}
+
+func checkConformance(t *testing.T, opts Opts, input, expectedOutput string, expectError bool) {
+	t.Helper()
+
+	for _, e := range engines {
+		if !e.handles(opts) {
+			continue
+		}
+		t.Run(e.name, func(t *testing.T) {
+			var output bytes.Buffer
+			err := e.run(strings.NewReader(input), &output, opts)
+
+			if expectError {
+				if err == nil {
+					t.Errorf("Expected error, but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if output.String() != expectedOutput {
+				t.Errorf("Expected:\n%q\nGot:\n%q", expectedOutput, output.String())
+			}
+		})
+	}
+}
+
 func TestCutProcessor(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -74,32 +159,13 @@ func TestCutProcessor(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var output bytes.Buffer
-			input := strings.NewReader(tt.input)
-
 			opts := Opts{
 				Fields:        tt.fields,
 				Delimiter:     tt.delimiter,
 				SeparatedOnly: tt.separatedOnly,
 			}
 
-			err := Process(input, &output, opts)
-
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("Expected error, but got none")
-				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
-			}
-
-			if output.String() != tt.expectedOutput {
-				t.Errorf("Expected:\n%q\nGot:\n%q", tt.expectedOutput, output.String())
-			}
+			checkConformance(t, opts, tt.input, tt.expectedOutput, tt.expectError)
 		})
 	}
 }
@@ -144,23 +210,12 @@ func TestEdgeCases(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var output bytes.Buffer
-			input := strings.NewReader(tt.input)
-
 			opts := Opts{
 				Fields:    tt.fields,
 				Delimiter: tt.delimiter,
 			}
 
-			err := Process(input, &output, opts)
-			if err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
-			}
-
-			if output.String() != tt.expectedOutput {
-				t.Errorf("Expected:\n%q\nGot:\n%q", tt.expectedOutput, output.String())
-			}
+			checkConformance(t, opts, tt.input, tt.expectedOutput, false)
 		})
 	}
 }
@@ -175,14 +230,16 @@ func TestParseFieldsErrorCases(t *testing.T) {
 		{"invalid start range", "a-3", true},
 		{"invalid end range", "1-b", true},
 		{"reverse range", "3-1", true},
-		{"negative field", "-1", true},
+		{"open start range", "-1", false},
+		{"open end range", "5-", false},
+		{"range without endpoints", "-", true},
 		{"negative range start", "-1-3", true},
 		{"negative range end", "1--3", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := parseFields(tt.fields)
+			_, err := parseList(tt.fields)
 			if tt.expectErr && err == nil {
 				t.Errorf("Expected error for %q, but got none", tt.fields)
 			}
@@ -192,3 +249,446 @@ func TestParseFieldsErrorCases(t *testing.T) {
 		})
 	}
 }
+
+func TestCharactersAndBytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           Opts
+		input          string
+		expectedOutput string
+	}{
+		{
+			name:           "ascii characters",
+			opts:           Opts{Characters: "1,3-4"},
+			input:          "abcdef\n",
+			expectedOutput: "acd\n",
+		},
+		{
+			name:           "cyrillic characters",
+			opts:           Opts{Characters: "1-3"},
+			input:          "привет\nмир\n",
+			expectedOutput: "при\nмир\n",
+		},
+		{
+			name:           "characters ignore delimiter",
+			opts:           Opts{Characters: "2", Delimiter: ":", SeparatedOnly: true},
+			input:          "a:b\nxyz\n",
+			expectedOutput: ":\ny\n",
+		},
+		{
+			name:           "bytes split multibyte characters",
+			opts:           Opts{Bytes: "1-3"},
+			input:          "привет\n",
+			expectedOutput: "п\xd1\n",
+		},
+		{
+			name:           "bytes with no split",
+			opts:           Opts{Bytes: "1-3", NoSplit: true},
+			input:          "привет\n",
+			expectedOutput: "п\n",
+		},
+		{
+			name:           "no split moves start back to character",
+			opts:           Opts{Bytes: "2-4", NoSplit: true},
+			input:          "привет\n",
+			expectedOutput: "пр\n",
+		},
+		{
+			name:           "no split drops ranges inside one character",
+			opts:           Opts{Bytes: "1", NoSplit: true},
+			input:          "привет\n",
+			expectedOutput: "\n",
+		},
+		{
+			name:           "bytes past end of line",
+			opts:           Opts{Bytes: "2-10"},
+			input:          "abc\n",
+			expectedOutput: "bc\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, tt.input, tt.expectedOutput, false)
+		})
+	}
+}
+
+func TestMultipleListsRejected(t *testing.T) {
+	checkConformance(t, Opts{Fields: "1", Characters: "1"}, "abc\n", "", true)
+}
+
+func TestKeepOrderWithComplementRejected(t *testing.T) {
+	checkConformance(t, Opts{Fields: "2,1", Delimiter: ":", KeepOrder: true, Complement: true}, "a:b:c\n", "", true)
+}
+
+func TestOpenRangesComplementAndOrder(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           Opts
+		input          string
+		expectedOutput string
+	}{
+		{
+			name:           "field to end",
+			opts:           Opts{Fields: "3-", Delimiter: ":"},
+			input:          "a:b:c:d:e\n",
+			expectedOutput: "c:d:e\n",
+		},
+		{
+			name:           "start to field",
+			opts:           Opts{Fields: "-2", Delimiter: ":"},
+			input:          "a:b:c:d:e\n",
+			expectedOutput: "a:b\n",
+		},
+		{
+			name:           "listed order is ignored by default",
+			opts:           Opts{Fields: "3,1", Delimiter: ":"},
+			input:          "a:b:c\n",
+			expectedOutput: "a:c\n",
+		},
+		{
+			name:           "keep order with duplicates",
+			opts:           Opts{Fields: "3,1,3-", Delimiter: ":", KeepOrder: true},
+			input:          "a:b:c:d\n",
+			expectedOutput: "c:a:c:d\n",
+		},
+		{
+			name:           "complement",
+			opts:           Opts{Fields: "2,4-", Delimiter: ":", Complement: true},
+			input:          "a:b:c:d:e\n",
+			expectedOutput: "a:c\n",
+		},
+		{
+			name:           "complement of characters",
+			opts:           Opts{Characters: "2-3", Complement: true},
+			input:          "привет\n",
+			expectedOutput: "пвет\n",
+		},
+		{
+			name:           "huge range",
+			opts:           Opts{Fields: "2-1000000000", Delimiter: ":"},
+			input:          "a:b:c\n",
+			expectedOutput: "b:c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, tt.input, tt.expectedOutput, false)
+		})
+	}
+}
+
+func TestDelimiterModes(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           Opts
+		input          string
+		expectedOutput string
+	}{
+		{
+			name:           "output delimiter",
+			opts:           Opts{Fields: "1,3", Delimiter: ":", OutputDelimiter: " | "},
+			input:          "a:b:c\n",
+			expectedOutput: "a | c\n",
+		},
+		{
+			name:           "regex delimiter",
+			opts:           Opts{Fields: "2-", RegexDelimiter: `[,;]\s*`},
+			input:          "a, b;c ,d\nnone\n",
+			expectedOutput: "b c  d\nnone\n",
+		},
+		{
+			name:           "regex delimiter with output delimiter",
+			opts:           Opts{Fields: "1,2", RegexDelimiter: `\s+`, OutputDelimiter: ","},
+			input:          "x   y\tz\n",
+			expectedOutput: "x,y\n",
+		},
+		{
+			name:           "whitespace collapses runs and trims edges",
+			opts:           Opts{Fields: "2,4", Whitespace: true},
+			input:          "  root     1  0.0 /sbin/init\nsingle\n",
+			expectedOutput: "1 /sbin/init\nsingle\n",
+		},
+		{
+			name:           "whitespace trims a single field",
+			opts:           Opts{Fields: "1", Whitespace: true},
+			input:          "  solo  \n\t\n",
+			expectedOutput: "solo\n\n",
+		},
+		{
+			name:           "whitespace separated only",
+			opts:           Opts{Fields: "1", Whitespace: true, SeparatedOnly: true},
+			input:          "  lonely  \nkey value\n",
+			expectedOutput: "key\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, tt.input, tt.expectedOutput, false)
+		})
+	}
+
+	t.Run("whitespace with regex delimiter", func(t *testing.T) {
+		checkConformance(t, Opts{Fields: "1", Whitespace: true, RegexDelimiter: ","}, "", "", true)
+	})
+}
+
+func TestCSVMode(t *testing.T) {
+	input := "id,name,email,note\n" +
+		"1,\"Doe, John\",john@example.com,\"multi\nline\"\n" +
+		"2,Ann,ann@example.com,\"said \"\"hi\"\"\"\n"
+
+	tests := []struct {
+		name           string
+		opts           Opts
+		input          string
+		expectedOutput string
+	}{
+		{
+			name:           "select by header name",
+			opts:           Opts{Fields: "name,email", CSV: true},
+			input:          input,
+			expectedOutput: "name,email\n\"Doe, John\",john@example.com\nAnn,ann@example.com\n",
+		},
+		{
+			name:           "quoted newlines and quotes survive",
+			opts:           Opts{Fields: "1,note", CSV: true},
+			input:          input,
+			expectedOutput: "id,note\n1,\"multi\nline\"\n2,\"said \"\"hi\"\"\"\n",
+		},
+		{
+			name:           "keep order by name",
+			opts:           Opts{Fields: "email,id", CSV: true, KeepOrder: true},
+			input:          input,
+			expectedOutput: "email,id\njohn@example.com,1\nann@example.com,2\n",
+		},
+		{
+			name:           "complement by name",
+			opts:           Opts{Fields: "note,email", CSV: true, Complement: true},
+			input:          input,
+			expectedOutput: "id,name\n1,\"Doe, John\"\n2,Ann\n",
+		},
+		{
+			name:           "custom delimiters",
+			opts:           Opts{Fields: "2", CSV: true, Delimiter: ";", OutputDelimiter: "\t"},
+			input:          "a;\"b;c\"\n",
+			expectedOutput: "b;c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, tt.input, tt.expectedOutput, false)
+		})
+	}
+
+	t.Run("unknown column", func(t *testing.T) {
+		checkConformance(t, Opts{Fields: "missing", CSV: true}, input, "", true)
+	})
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	_, err := New(Opts{Fields: "1, 3-x,5"})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected *ParseError, got %v", err)
+	}
+	if parseErr.Pos != 4 || parseErr.Token != "3-x" {
+		t.Errorf("Expected token %q at position 4, got %q at %d", "3-x", parseErr.Token, parseErr.Pos)
+	}
+}
+
+type failingWriter struct{}
+
+var errWriteFailed = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errWriteFailed }
+
+func TestWriteErrorsPropagate(t *testing.T) {
+	input := strings.Repeat("a:b:c\n", outputBufferSize)
+
+	for _, opts := range []Opts{{Fields: "1", Delimiter: ":"}, {Fields: "1", CSV: true}} {
+		err := Process(strings.NewReader(input), failingWriter{}, opts)
+		if !errors.Is(err, errWriteFailed) {
+			t.Errorf("Expected write error for %+v, got %v", opts, err)
+		}
+	}
+}
+
+func TestRecordTerminators(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+
+	tests := []struct {
+		name           string
+		opts           Opts
+		input          string
+		expectedOutput string
+	}{
+		{
+			name:           "zero terminated",
+			opts:           Opts{Fields: "2", Delimiter: "/", ZeroTerminated: true},
+			input:          "a/b\x00c/d\ne\x00",
+			expectedOutput: "b\x00d\ne\x00",
+		},
+		{
+			name:           "zero terminated without final NUL",
+			opts:           Opts{Characters: "1", ZeroTerminated: true},
+			input:          "ab\x00cd",
+			expectedOutput: "a\x00c\x00",
+		},
+		{
+			name:           "missing final newline",
+			opts:           Opts{Fields: "1", Delimiter: ":"},
+			input:          "a:b\nc:d",
+			expectedOutput: "a\nc\n",
+		},
+		{
+			name:           "carriage returns",
+			opts:           Opts{Fields: "2", Delimiter: ":"},
+			input:          "a:b\r\nc:d\r\n",
+			expectedOutput: "b\nd\n",
+		},
+		{
+			name:           "line longer than a scanner token",
+			opts:           Opts{Fields: "2", Delimiter: ":"},
+			input:          "a:" + long + ":c\n",
+			expectedOutput: long + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, tt.input, tt.expectedOutput, false)
+		})
+	}
+
+	t.Run("csv rejects zero terminated", func(t *testing.T) {
+		checkConformance(t, Opts{Fields: "1", CSV: true, ZeroTerminated: true}, "", "", true)
+	})
+}
+
+func TestJSONLMode(t *testing.T) {
+	input := `{"id":1,"user":{"name":"ann","age":30},"tags":["a","b"]}` + "\n" +
+		"\n" +
+		`{"id":2, "user": {"name": "bob\tsmith"}}` + "\n" +
+		`{"id":3}` + "\n"
+
+	tests := []struct {
+		name           string
+		opts           Opts
+		expectedOutput string
+	}{
+		{
+			name: "projection keeps nesting",
+			opts: Opts{JSONL: true, Fields: ".id,.user.name"},
+			expectedOutput: `{"id":1,"user":{"name":"ann"}}` + "\n" +
+				`{"id":2,"user":{"name":"bob\tsmith"}}` + "\n" +
+				`{"id":3}` + "\n",
+		},
+		{
+			name: "placeholder for missing keys",
+			opts: Opts{JSONL: true, Fields: ".user.age", Missing: "null"},
+			expectedOutput: `{"user":{"age":30}}` + "\n" +
+				`{"user":{"age":null}}` + "\n" +
+				`{"user":{"age":null}}` + "\n",
+		},
+		{
+			name: "complement drops the listed paths",
+			opts: Opts{JSONL: true, Fields: ".user.age,.tags", Complement: true},
+			expectedOutput: `{"id":1,"user":{"name":"ann"}}` + "\n" +
+				`{"id":2,"user":{"name":"bob\tsmith"}}` + "\n" +
+				`{"id":3}` + "\n",
+		},
+		{
+			name:           "tsv rows",
+			opts:           Opts{JSONL: true, Fields: ".id,.user.name,.tags", To: "tsv", Missing: "-"},
+			expectedOutput: "1\tann\t[\"a\",\"b\"]\n2\tbob\\tsmith\t-\n3\t-\t-\n",
+		},
+		{
+			name:           "tsv with output delimiter",
+			opts:           Opts{JSONL: true, Fields: ".user.name,.id", To: "tsv", OutputDelimiter: ","},
+			expectedOutput: "ann,1\nbob\\tsmith,2\n,3\n",
+		},
+		{
+			name:           "empty list selects the whole record",
+			opts:           Opts{JSONL: true, To: "tsv", Fields: ""},
+			expectedOutput: "1\t{\"name\":\"ann\",\"age\":30}\t[\"a\",\"b\"]\n2\t{\"name\":\"bob\\tsmith\"}\n3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, input, tt.expectedOutput, false)
+		})
+	}
+
+	errorCases := []struct {
+		name  string
+		opts  Opts
+		input string
+	}{
+		{"path without dot", Opts{JSONL: true, Fields: "id"}, ""},
+		{"empty key", Opts{JSONL: true, Fields: ".user..name"}, ""},
+		{"unknown format", Opts{JSONL: true, Fields: ".id", To: "xml"}, ""},
+		{"to without jsonl", Opts{Fields: "1", To: "tsv"}, ""},
+		{"with characters", Opts{JSONL: true, Characters: "1"}, ""},
+		{"not an object", Opts{JSONL: true, Fields: ".id"}, "[1,2]\n"},
+		{"trailing data", Opts{JSONL: true, Fields: ".id"}, `{"id":1} {"id":2}` + "\n"},
+		{"malformed", Opts{JSONL: true, Fields: ".id"}, `{"id":` + "\n"},
+	}
+
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			checkConformance(t, tt.opts, tt.input, "", true)
+		})
+	}
+}
+
+func TestJSONLErrorsCarryPosition(t *testing.T) {
+	_, err := New(Opts{JSONL: true, Fields: ".id,user"})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Pos != 5 {
+		t.Errorf("Expected *ParseError at position 5, got %v", err)
+	}
+
+	err = Process(strings.NewReader("{\"id\":1}\nnope\n"), &bytes.Buffer{}, Opts{JSONL: true, Fields: ".id"})
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Expected error on line 2, got %v", err)
+	}
+}
+
+func TestJSONLBadLinesAreSkipped(t *testing.T) {
+	input := "{\"id\":1}\nnope\n{\"id\":3}\n[4]\n{\"id\":5}\n"
+
+	p, err := New(Opts{JSONL: true, Fields: ".id"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Each error comes right after the output of the lines before it.
+	var output bytes.Buffer
+	var reported []string
+	p.OnRecordError = func(err error) {
+		reported = append(reported, output.String()+"! "+err.Error())
+	}
+	err = p.Process(strings.NewReader(input), &output)
+
+	expected := "{\"id\":1}\n{\"id\":3}\n{\"id\":5}\n"
+	if output.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, output.String())
+	}
+	if len(reported) != 2 ||
+		!strings.HasPrefix(reported[0], "{\"id\":1}\n! line 2:") ||
+		!strings.HasPrefix(reported[1], "{\"id\":1}\n{\"id\":3}\n! line 4:") {
+		t.Errorf("Expected errors on lines 2 and 4 after their output, got %q", reported)
+	}
+
+	var recordErrs *RecordsError
+	if !errors.As(err, &recordErrs) || recordErrs.Count != 2 || !strings.Contains(err.Error(), "line 2:") {
+		t.Errorf("Expected a summary of 2 bad records, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package cut
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errMultipleLists         = errors.New("only one type of list may be specified")
+	errKeepOrderComplement   = errors.New("--keep-order cannot be combined with --complement")
+	errConflictingDelimiters = errors.New("--whitespace and --regex-delimiter cannot be combined")
+	errCSVPositions          = errors.New("--csv works with fields only, not characters or bytes")
+	errCSVDelimiters         = errors.New("--csv cannot be combined with --whitespace or --regex-delimiter")
+	errCSVZeroTerminated     = errors.New("--csv cannot be combined with --zero-terminated")
+	errJSONLOptions          = errors.New("--jsonl works with -f JSON paths only")
+	errToWithoutJSONL        = errors.New("--to and --missing require --jsonl")
+	errNotJSONObject         = errors.New("record is not a JSON object")
+)
+
+// RecordsError reports the records that Processor.Process skipped because
+// they could not be cut, with the first of them.
+type RecordsError struct {
+	Count int
+	First error
+}
+
+func (e *RecordsError) Error() string {
+	if e.Count == 1 {
+		return e.First.Error()
+	}
+	return fmt.Sprintf("%s (and %d more bad records)", e.First, e.Count-1)
+}
+
+func (e *RecordsError) Unwrap() error {
+	return e.First
+}
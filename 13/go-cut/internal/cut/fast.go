@@ -0,0 +1,139 @@
+package cut
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// fieldCutter is the engine for the common case of -f with a literal
+// delimiter. It works on the bytes of a record without allocating per
+// field, and only scans a record as far as the highest selected field.
+type fieldCutter struct {
+	delim         []byte
+	outDelim      []byte
+	sel           selection
+	limit         int // highest field that can be printed
+	separatedOnly bool
+	terminator    byte
+
+	bounds []int // start and end offsets of the fields found so far
+	buf    []byte
+}
+
+func newFieldCutter(opts validOpts) *fieldCutter {
+	limit := 0
+	if opts.sel.complement {
+		limit = openEnd
+	}
+	for _, s := range opts.sel.merged {
+		limit = max(limit, s.hi)
+	}
+
+	return &fieldCutter{
+		delim:         []byte(opts.literal),
+		outDelim:      []byte(opts.outDelimiter),
+		sel:           opts.sel,
+		limit:         limit,
+		separatedOnly: opts.separatedOnly,
+		terminator:    opts.terminator,
+	}
+}
+
+func (c *fieldCutter) process(r *bufio.Reader, w *bufio.Writer) error {
+	for {
+		record, readErr := c.readRecord(r)
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+		if len(record) == 0 {
+			return nil
+		}
+
+		if err := c.cut(w, c.trimTerminator(record)); err != nil {
+			return err
+		}
+
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+// readRecord returns the next record including its terminator. Records
+// that fit into the reader's buffer are not copied.
+func (c *fieldCutter) readRecord(r *bufio.Reader) ([]byte, error) {
+	record, err := r.ReadSlice(c.terminator)
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return record, err
+	}
+
+	c.buf = append(c.buf[:0], record...)
+	for errors.Is(err, bufio.ErrBufferFull) {
+		record, err = r.ReadSlice(c.terminator)
+		c.buf = append(c.buf, record...)
+	}
+	return c.buf, err
+}
+
+func (c *fieldCutter) trimTerminator(record []byte) []byte {
+	record = bytes.TrimSuffix(record, []byte{c.terminator})
+	if c.terminator == '\n' {
+		record = bytes.TrimSuffix(record, []byte{'\r'})
+	}
+	return record
+}
+
+// cut writes the selected fields of one record. It behaves like cutLine.
+func (c *fieldCutter) cut(w *bufio.Writer, line []byte) error {
+	first := bytes.Index(line, c.delim)
+	if first < 0 {
+		if c.separatedOnly {
+			return nil
+		}
+		return c.writeRecord(w, line)
+	}
+
+	if c.sel.empty() {
+		return c.writeRecord(w, line)
+	}
+
+	c.bounds = append(c.bounds[:0], 0, first)
+	for start := first + len(c.delim); len(c.bounds)/2 < c.limit; {
+		end := bytes.Index(line[start:], c.delim)
+		if end < 0 {
+			c.bounds = append(c.bounds, start, len(line))
+			break
+		}
+		c.bounds = append(c.bounds, start, start+end)
+		start += end + len(c.delim)
+	}
+
+	var err error
+	written := false
+	c.sel.each(len(c.bounds)/2, func(pos int) {
+		if err != nil {
+			return
+		}
+		if written {
+			_, err = w.Write(c.outDelim)
+		}
+		if err == nil {
+			_, err = w.Write(line[c.bounds[2*pos-2]:c.bounds[2*pos-1]])
+		}
+		written = true
+	})
+
+	if err != nil || !written {
+		return err
+	}
+	return w.WriteByte(c.terminator)
+}
+
+func (c *fieldCutter) writeRecord(w *bufio.Writer, line []byte) error {
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+	return w.WriteByte(c.terminator)
+}
@@ -0,0 +1,220 @@
+package cut
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stringProcess runs every line through cutLine, the string-based path the
+// byte engine replaces for literal delimiters.
+func stringProcess(r io.Reader, w io.Writer, opts Opts) error {
+	p, err := New(opts)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		line, ok, err := p.cutLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if ok {
+			_, _ = fmt.Fprintln(w, line)
+		}
+	}
+	return scanner.Err()
+}
+
+func TestFieldCutterMatchesStringPath(t *testing.T) {
+	input := "a:b:c:d:e\n" +
+		"no delimiter\n" +
+		"::\n" +
+		"x::y:\n" +
+		"1:2\r\n" +
+		"only:" + strings.Repeat("z", 100_000) + ":end\n" +
+		"last:line"
+
+	lists := []string{"", "1", "2", "5", "9", "1,3", "2-", "-2", "3,1", "1-2,4-", "2-3,100"}
+
+	for _, list := range lists {
+		for _, variant := range []Opts{
+			{},
+			{SeparatedOnly: true},
+			{Complement: true},
+			{KeepOrder: true},
+			{OutputDelimiter: " | "},
+		} {
+			opts := variant
+			opts.Fields = list
+			opts.Delimiter = ":"
+
+			var expected, got bytes.Buffer
+			if err := stringProcess(strings.NewReader(input), &expected, opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := Process(strings.NewReader(input), &got, opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got.String() != expected.String() {
+				t.Errorf("%+v:\nExpected:\n%q\nGot:\n%q", opts, expected.String(), got.String())
+			}
+		}
+	}
+}
+
+func TestFieldCutterMultiByteDelimiter(t *testing.T) {
+	checkConformance(t, Opts{Fields: "2,4", Delimiter: "::"}, "a::b::c::d\na:b\n", "b::d\na:b\n", false)
+}
+
+func TestFieldCutterStopsAtHighestField(t *testing.T) {
+	c := newFieldCutter(mustParse(t, Opts{Fields: "2", Delimiter: ","}))
+
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	if err := c.cut(w, []byte("a,b,c,d,e,f")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_ = w.Flush()
+
+	if len(c.bounds) != 4 {
+		t.Errorf("Expected 2 fields to be scanned, got %d", len(c.bounds)/2)
+	}
+	if out.String() != "b\n" {
+		t.Errorf("Expected %q, got %q", "b\n", out.String())
+	}
+}
+
+func mustParse(t *testing.T, opts Opts) validOpts {
+	t.Helper()
+
+	valid, err := parseOpts(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return valid
+}
+
+// legacyProcess is the engine go-cut started with: a bufio.Scanner, a
+// string per line and strings.Split/Join per selected line. It is kept to
+// benchmark the byte engine against.
+func legacyProcess(r io.Reader, w io.Writer, rawOpts ...Opts) error {
+	opts := rawOpts[0]
+	spans, err := parseList(opts.Fields)
+	if err != nil {
+		return err
+	}
+	// Open ranges are capped, the legacy engine expanded every position.
+	fields := make(map[int]struct{})
+	for _, s := range spans {
+		for i := s.lo; i <= s.hi && i <= 1000; i++ {
+			fields[i] = struct{}{}
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, opts.Delimiter) {
+			_, _ = fmt.Fprintln(w, line)
+			continue
+		}
+
+		var outputFields []string
+		for i, field := range strings.Split(line, opts.Delimiter) {
+			if _, ok := fields[i+1]; ok {
+				outputFields = append(outputFields, field)
+			}
+		}
+		if len(outputFields) > 0 {
+			_, _ = fmt.Fprintln(w, strings.Join(outputFields, opts.Delimiter))
+		}
+	}
+	return scanner.Err()
+}
+
+// The benchmarks cut GO_CUT_BENCH_SIZE bytes (64MB by default) of
+// generated 12-field TSV held in memory.
+
+var (
+	benchOnce  sync.Once
+	benchInput []byte
+	benchErr   error
+)
+
+func benchData(b *testing.B) []byte {
+	b.Helper()
+
+	benchOnce.Do(func() {
+		size := 64 << 20
+		if env := os.Getenv("GO_CUT_BENCH_SIZE"); env != "" {
+			if size, benchErr = strconv.Atoi(env); benchErr != nil {
+				return
+			}
+		}
+
+		var buf bytes.Buffer
+		for i := 0; buf.Len() < size; i++ {
+			fmt.Fprintf(&buf, "%d\tuser%d\t%d.%d.%d.%d\tGET\t/api/v1/items/%d\t200\t%d\tMozilla/5.0\t-\t%d\tok\tend\n",
+				i, i%1000, i%256, i%7, i%13, i%250, i, i*7%5000, i%97)
+		}
+		benchInput = buf.Bytes()
+	})
+
+	if benchErr != nil {
+		b.Fatalf("GO_CUT_BENCH_SIZE: %v", benchErr)
+	}
+	return benchInput
+}
+
+func benchmarkCut(b *testing.B, process func(io.Reader, io.Writer, ...Opts) error, opts Opts) {
+	data := benchData(b)
+	opts.Delimiter = "\t"
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := process(bytes.NewReader(data), io.Discard, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLegacy_FirstField(b *testing.B) {
+	benchmarkCut(b, legacyProcess, Opts{Fields: "1"})
+}
+
+func BenchmarkProcess_FirstField(b *testing.B) {
+	benchmarkCut(b, Process, Opts{Fields: "1"})
+}
+
+func BenchmarkLegacy_Range(b *testing.B) {
+	benchmarkCut(b, legacyProcess, Opts{Fields: "2-5,7"})
+}
+
+func BenchmarkProcess_Range(b *testing.B) {
+	benchmarkCut(b, Process, Opts{Fields: "2-5,7"})
+}
+
+func BenchmarkLegacy_LastField(b *testing.B) {
+	benchmarkCut(b, legacyProcess, Opts{Fields: "12"})
+}
+
+func BenchmarkProcess_LastField(b *testing.B) {
+	benchmarkCut(b, Process, Opts{Fields: "12"})
+}
+
+func BenchmarkProcess_Complement(b *testing.B) {
+	benchmarkCut(b, Process, Opts{Fields: "5", Complement: true})
+}
@@ -0,0 +1,248 @@
+package cut
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// jsonPath is a path of object keys, ".user.name" being {"user", "name"}.
+type jsonPath []string
+
+// parseJSONPaths parses a -f list of JSON paths such as ".id,.user.name".
+// Bad paths are reported as *ParseError, like bad field numbers.
+func parseJSONPaths(list string) ([]jsonPath, error) {
+	var paths []jsonPath
+
+	for _, tok := range splitList(list) {
+		fail := func(msg string) ([]jsonPath, error) {
+			return nil, &ParseError{List: list, Pos: tok.pos, Token: tok.text, Msg: msg}
+		}
+
+		if !strings.HasPrefix(tok.text, ".") {
+			return fail("JSON path must start with '.'")
+		}
+		keys := strings.Split(tok.text[1:], ".")
+		if slices.Contains(keys, "") {
+			return fail("empty key in JSON path")
+		}
+		paths = append(paths, keys)
+	}
+
+	return paths, nil
+}
+
+// descend reports whether paths select key itself and returns the paths
+// that continue below it.
+func descend(paths []jsonPath, key string) (bool, []jsonPath) {
+	whole := false
+	var sub []jsonPath
+
+	for _, path := range paths {
+		if path[0] != key {
+			continue
+		}
+		if len(path) == 1 {
+			whole = true
+		} else {
+			sub = append(sub, path[1:])
+		}
+	}
+
+	return whole, sub
+}
+
+// jsonMember is one key of a JSON object. Objects are kept as member
+// slices so that the input key order survives projection.
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+func decodeObject(data []byte) ([]jsonMember, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errNotJSONObject
+	}
+
+	var members []jsonMember
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, jsonMember{key: tok.(string), value: value})
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errNotJSONObject
+	}
+
+	return members, nil
+}
+
+func encodeObject(members []jsonMember) json.RawMessage {
+	var b bytes.Buffer
+
+	b.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(m.key)
+		b.Write(key)
+		b.WriteByte(':')
+		_ = json.Compact(&b, m.value)
+	}
+	b.WriteByte('}')
+
+	return b.Bytes()
+}
+
+// memberValue returns the value of key. As with encoding/json, the last of
+// duplicate keys wins.
+func memberValue(members []jsonMember, key string) (json.RawMessage, bool) {
+	for i := len(members) - 1; i >= 0; i-- {
+		if members[i].key == key {
+			return members[i].value, true
+		}
+	}
+	return nil, false
+}
+
+func lookup(members []jsonMember, path jsonPath) (json.RawMessage, bool) {
+	for {
+		value, ok := memberValue(members, path[0])
+		if !ok || len(path) == 1 {
+			return value, ok
+		}
+
+		var err error
+		if members, err = decodeObject(value); err != nil {
+			return nil, false
+		}
+		path = path[1:]
+	}
+}
+
+// project keeps only the values at paths, nested as they are in the input,
+// with keys in the order they are first listed. Missing values are left
+// out, or replaced by missing when it is set.
+func project(members []jsonMember, paths []jsonPath, missing json.RawMessage) []jsonMember {
+	var out []jsonMember
+	var seen []string
+
+	for _, path := range paths {
+		key := path[0]
+		if slices.Contains(seen, key) {
+			continue
+		}
+		seen = append(seen, key)
+
+		value, ok := memberValue(members, key)
+		whole, sub := descend(paths, key)
+
+		switch {
+		case whole && ok:
+			out = append(out, jsonMember{key: key, value: value})
+		case whole && missing != nil:
+			out = append(out, jsonMember{key: key, value: missing})
+		case !whole:
+			// Values that are not objects have no keys to select.
+			nested, _ := decodeObject(value)
+			if inner := project(nested, sub, missing); len(inner) > 0 {
+				out = append(out, jsonMember{key: key, value: encodeObject(inner)})
+			}
+		}
+	}
+
+	return out
+}
+
+// without drops the values at paths and keeps everything else in input
+// order. It is the --complement of project.
+func without(members []jsonMember, paths []jsonPath) []jsonMember {
+	var out []jsonMember
+
+	for _, m := range members {
+		whole, sub := descend(paths, m.key)
+		if whole {
+			continue
+		}
+
+		if nested, err := decodeObject(m.value); err == nil && len(sub) > 0 {
+			m.value = encodeObject(without(nested, sub))
+		}
+		out = append(out, m)
+	}
+
+	return out
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// tsvValue formats a value as a TSV cell: strings are unquoted and escaped,
+// everything else is written as compact JSON.
+func tsvValue(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return tsvEscaper.Replace(s)
+	}
+
+	var b bytes.Buffer
+	_ = json.Compact(&b, value)
+	return b.String()
+}
+
+// cutJSONLine projects one JSON Lines record. Blank lines are skipped.
+func (p *Processor) cutJSONLine(line string) (string, bool, error) {
+	if strings.TrimSpace(line) == "" {
+		return "", false, nil
+	}
+
+	members, err := decodeObject([]byte(line))
+	if err != nil {
+		return "", false, err
+	}
+
+	o := p.opts.jsonl
+	if o.complement {
+		members = without(members, o.paths)
+	}
+
+	if !o.tsv {
+		if !o.complement {
+			members = project(members, o.paths, o.missing)
+		}
+		return string(encodeObject(members)), true, nil
+	}
+
+	var cells []string
+	if o.complement {
+		for _, m := range members {
+			cells = append(cells, tsvValue(m.value))
+		}
+	} else {
+		for _, path := range o.paths {
+			value, ok := lookup(members, path)
+			if !ok {
+				cells = append(cells, o.missingText)
+				continue
+			}
+			cells = append(cells, tsvValue(value))
+		}
+	}
+
+	return strings.Join(cells, p.opts.outDelimiter), true, nil
+}
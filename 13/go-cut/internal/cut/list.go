@@ -0,0 +1,196 @@
+package cut
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// openEnd is the upper bound of ranges like "5-" that run to the end of
+// the line.
+const openEnd = math.MaxInt
+
+// span is an inclusive 1-based range of fields, characters or bytes.
+type span struct {
+	lo, hi int
+}
+
+// ParseError reports a bad token in a field, character or byte list.
+type ParseError struct {
+	List  string // the whole list as given
+	Pos   int    // 1-based offset of Token in List
+	Token string
+	Msg   string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s (position %d)", e.Msg, e.Token, e.Pos)
+}
+
+type listToken struct {
+	text string
+	pos  int
+}
+
+// splitList splits a list on commas, remembering where every trimmed
+// token starts. Empty tokens are skipped.
+func splitList(list string) []listToken {
+	var tokens []listToken
+
+	offset := 0
+	for _, part := range strings.Split(list, ",") {
+		text := strings.TrimSpace(part)
+		if text != "" {
+			tokens = append(tokens, listToken{text: text, pos: offset + strings.Index(part, text) + 1})
+		}
+		offset += len(part) + 1
+	}
+
+	return tokens
+}
+
+// parseList parses a LIST such as "1,3-5,7-" shared by -f, -c and -b.
+// "-N" means 1-N and "N-" means from N to the end of the line.
+// Spans are returned in the order they are listed; positions below 1 are
+// dropped.
+func parseList(list string) ([]span, error) {
+	var result []span
+
+	for _, tok := range splitList(list) {
+		s, ok, err := parseSpan(list, tok)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+// parseSpan parses a single number or range. ok is false when the token
+// only selects positions below 1.
+func parseSpan(list string, tok listToken) (span, bool, error) {
+	fail := func(msg string) (span, bool, error) {
+		return span{}, false, &ParseError{List: list, Pos: tok.pos, Token: tok.text, Msg: msg}
+	}
+
+	if !strings.Contains(tok.text, "-") {
+		num, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return fail("invalid field number")
+		}
+		return span{lo: num, hi: num}, num > 0, nil
+	}
+
+	rangeParts := strings.Split(tok.text, "-")
+	if len(rangeParts) != 2 {
+		return fail("invalid range format")
+	}
+
+	startStr := strings.TrimSpace(rangeParts[0])
+	endStr := strings.TrimSpace(rangeParts[1])
+	if startStr == "" && endStr == "" {
+		return fail("invalid range with no endpoint")
+	}
+
+	start, end := 1, openEnd
+	var err error
+
+	if startStr != "" {
+		if start, err = strconv.Atoi(startStr); err != nil {
+			return fail("invalid start of range")
+		}
+	}
+
+	if endStr != "" {
+		if end, err = strconv.Atoi(endStr); err != nil {
+			return fail("invalid end of range")
+		}
+	}
+
+	if start > end {
+		return fail("range start cannot be greater than end")
+	}
+
+	return span{lo: max(start, 1), hi: end}, end > 0, nil
+}
+
+// mergeSpans returns the spans sorted, with overlapping and adjacent
+// ranges joined.
+func mergeSpans(spans []span) []span {
+	merged := slices.Clone(spans)
+	slices.SortFunc(merged, func(a, b span) int { return a.lo - b.lo })
+
+	out := merged[:0]
+	for _, s := range merged {
+		if n := len(out); n > 0 && (out[n-1].hi == openEnd || s.lo <= out[n-1].hi+1) {
+			out[n-1].hi = max(out[n-1].hi, s.hi)
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// selection decides which positions of a line are printed and in which
+// order. Ranges are never expanded, so "1-1000000" costs one span.
+type selection struct {
+	listed     []span
+	merged     []span
+	complement bool
+	keepOrder  bool
+}
+
+func newSelection(spans []span, complement, keepOrder bool) selection {
+	return selection{
+		listed:     spans,
+		merged:     mergeSpans(spans),
+		complement: complement,
+		keepOrder:  keepOrder && !complement,
+	}
+}
+
+func (s selection) empty() bool {
+	return len(s.listed) == 0 && !s.complement
+}
+
+// each calls fn for every selected position of a line with n positions.
+// Positions come in ascending order, or in the listed order with
+// duplicates when keepOrder is set.
+func (s selection) each(n int, fn func(pos int)) {
+	switch {
+	case s.keepOrder:
+		for _, sp := range s.listed {
+			for i := sp.lo; i <= min(sp.hi, n); i++ {
+				fn(i)
+			}
+		}
+	case s.complement:
+		next := 1
+		for _, sp := range s.merged {
+			for i := next; i < min(sp.lo, n+1); i++ {
+				fn(i)
+			}
+			if sp.hi >= n {
+				return
+			}
+			next = sp.hi + 1
+		}
+		for i := next; i <= n; i++ {
+			fn(i)
+		}
+	default:
+		for _, sp := range s.merged {
+			if sp.lo > n {
+				return
+			}
+			for i := sp.lo; i <= min(sp.hi, n); i++ {
+				fn(i)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package cut
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+type validOpts struct {
+	mode          mode
+	sel           selection
+	split         splitFunc
+	literal       string // the -d delimiter when fields are split on it
+	outDelimiter  string
+	separatedOnly bool
+	noSplit       bool
+	terminator    byte // record terminator, '\n' or NUL with -z
+
+	csv         bool
+	csvComma    rune
+	csvOutComma rune
+	csvNames    bool   // the field list names header columns
+	csvList     string // field list resolved against the header
+
+	jsonl *jsonOpts // nil unless --jsonl
+}
+
+type jsonOpts struct {
+	paths       []jsonPath
+	complement  bool
+	tsv         bool
+	missing     json.RawMessage // nil leaves missing keys out of JSON output
+	missingText string
+}
+
+func parseOpts(raw Opts) (validOpts, error) {
+	result := validOpts{
+		separatedOnly: raw.SeparatedOnly,
+		noSplit:       raw.NoSplit,
+		terminator:    '\n',
+	}
+	if raw.ZeroTerminated {
+		result.terminator = 0
+	}
+
+	if err := result.setupDelimiters(raw); err != nil {
+		return validOpts{}, err
+	}
+
+	lists := 0
+	for _, list := range []string{raw.Fields, raw.Characters, raw.Bytes} {
+		if list != "" {
+			lists++
+		}
+	}
+	if lists > 1 {
+		return validOpts{}, errMultipleLists
+	}
+	if raw.KeepOrder && raw.Complement {
+		// What is left out has no listed order to keep.
+		return validOpts{}, errKeepOrderComplement
+	}
+
+	list := raw.Fields
+	switch {
+	case raw.Characters != "":
+		result.mode = modeChars
+		list = raw.Characters
+	case raw.Bytes != "":
+		result.mode = modeBytes
+		list = raw.Bytes
+	}
+
+	if raw.JSONL {
+		return result, result.setupJSONL(raw)
+	}
+	if raw.To != "" || raw.Missing != "" {
+		return validOpts{}, errToWithoutJSONL
+	}
+
+	if raw.CSV {
+		return result, result.setupCSV(raw)
+	}
+
+	spans, err := parseList(list)
+	if err != nil {
+		return validOpts{}, err
+	}
+	result.sel = newSelection(spans, raw.Complement, raw.KeepOrder)
+
+	return result, nil
+}
+
+func (o *validOpts) setupCSV(raw Opts) error {
+	if o.mode != modeFields {
+		return errCSVPositions
+	}
+	if raw.Whitespace || raw.RegexDelimiter != "" {
+		return errCSVDelimiters
+	}
+	if raw.ZeroTerminated {
+		return errCSVZeroTerminated
+	}
+
+	var err error
+	if o.csvComma, err = csvDelimiter(raw.Delimiter, ','); err != nil {
+		return err
+	}
+	if o.csvOutComma, err = csvDelimiter(raw.OutputDelimiter, o.csvComma); err != nil {
+		return err
+	}
+
+	o.csv = true
+	spans, err := parseList(raw.Fields)
+	if err != nil {
+		o.csvNames = true
+		o.csvList = raw.Fields
+	}
+	o.sel = newSelection(spans, raw.Complement, raw.KeepOrder)
+
+	return nil
+}
+
+// setupJSONL reads -f as JSON paths. An empty list selects the whole
+// record, as it does for plain fields.
+func (o *validOpts) setupJSONL(raw Opts) error {
+	if o.mode != modeFields || raw.CSV || raw.Whitespace || raw.RegexDelimiter != "" {
+		return errJSONLOptions
+	}
+
+	paths, err := parseJSONPaths(raw.Fields)
+	if err != nil {
+		return err
+	}
+
+	j := &jsonOpts{
+		paths:       paths,
+		complement:  raw.Complement || len(paths) == 0,
+		missingText: raw.Missing,
+	}
+
+	switch raw.To {
+	case "", "json":
+	case "tsv":
+		j.tsv = true
+	default:
+		return fmt.Errorf("unknown output format %q, expected json or tsv", raw.To)
+	}
+
+	// A placeholder that is valid JSON, like null or 0, is used as is;
+	// anything else becomes a string.
+	if raw.Missing != "" {
+		j.missing = json.RawMessage(raw.Missing)
+		if !json.Valid(j.missing) {
+			j.missing, _ = json.Marshal(raw.Missing)
+		}
+	}
+
+	o.outDelimiter = "\t"
+	if raw.OutputDelimiter != "" {
+		o.outDelimiter = raw.OutputDelimiter
+	}
+	o.jsonl = j
+
+	return nil
+}
+
+func (o *validOpts) setupDelimiters(raw Opts) error {
+	if raw.Whitespace && raw.RegexDelimiter != "" {
+		return errConflictingDelimiters
+	}
+
+	switch {
+	case raw.Whitespace:
+		o.split = whitespaceSplitter()
+		o.outDelimiter = " "
+	case raw.RegexDelimiter != "":
+		re, err := regexp.Compile(raw.RegexDelimiter)
+		if err != nil {
+			return fmt.Errorf("invalid regex delimiter: %w", err)
+		}
+		o.split = regexSplitter(re)
+		o.outDelimiter = " "
+	default:
+		delimiter := "\t"
+		if raw.Delimiter != "" {
+			delimiter = raw.Delimiter
+		}
+		o.split = literalSplitter(delimiter)
+		o.literal = delimiter
+		o.outDelimiter = delimiter
+	}
+
+	if raw.OutputDelimiter != "" {
+		o.outDelimiter = raw.OutputDelimiter
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cut
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type mode int
+
+const (
+	modeFields mode = iota
+	modeChars
+	modeBytes
+)
+
+// selectChars keeps the selected character positions. Characters are
+// UTF-8 runes, so Cyrillic text is cut by letters, not by bytes.
+func selectChars(line string, sel selection) string {
+	var offsets []int
+	for i := range line {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(line))
+
+	var b strings.Builder
+	sel.each(len(offsets)-1, func(pos int) {
+		b.WriteString(line[offsets[pos-1]:offsets[pos]])
+	})
+
+	return b.String()
+}
+
+// selectBytes keeps the selected byte positions. With noSplit every range
+// is narrowed as POSIX describes for -n: its start moves back to the first
+// byte of a character and its end moves back before a character that does
+// not end inside the range, so multibyte characters are never split.
+func selectBytes(line string, sel selection, noSplit bool) string {
+	if noSplit {
+		sel = newSelection(adjustToCharacters(line, sel.listed), sel.complement, sel.keepOrder)
+	}
+
+	var b strings.Builder
+	sel.each(len(line), func(pos int) {
+		b.WriteByte(line[pos-1])
+	})
+
+	return b.String()
+}
+
+func adjustToCharacters(line string, spans []span) []span {
+	adjusted := make([]span, 0, len(spans))
+
+	for _, s := range spans {
+		lo, hi := s.lo, min(s.hi, len(line))
+
+		for lo > 1 && lo <= len(line) && !utf8.RuneStart(line[lo-1]) {
+			lo--
+		}
+		if hi < len(line) && !utf8.RuneStart(line[hi]) {
+			for hi > 0 && !utf8.RuneStart(line[hi-1]) {
+				hi--
+			}
+			hi--
+		}
+
+		if lo <= hi {
+			adjusted = append(adjusted, span{lo: lo, hi: hi})
+		}
+	}
+
+	return adjusted
+}
+
+func selectPositions(line string, m mode, sel selection, noSplit bool) string {
+	if m == modeChars {
+		return selectChars(line, sel)
+	}
+	return selectBytes(line, sel, noSplit)
+}
+
+// selectFields picks the selected fields out of a split line.
+func selectFields(fields []string, sel selection) []string {
+	var outputFields []string
+	sel.each(len(fields), func(pos int) {
+		outputFields = append(outputFields, fields[pos-1])
+	})
+	return outputFields
+}
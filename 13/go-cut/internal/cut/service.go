@@ -2,67 +2,186 @@ package cut
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"wb-tech-l2/13/go-cut/internal/config"
 )
 
+const outputBufferSize = 256 * 1024
+
+// Processor is the cutting engine shared by Process and the go-cut command.
 type Processor struct {
-	fields        map[int]struct{}
-	delimiter     string
-	separatedOnly bool
+	// OnRecordError, when set, is called with each record that cannot be
+	// cut as soon as it is met, after the output of the records before it
+	// has been written.
+	OnRecordError func(err error)
+
+	opts validOpts
 }
 
-func NewProcessor(cfg *config.Cut) (*Processor, error) {
-	fieldSet, err := parseFields(cfg.Fields)
+// New validates opts. Malformed lists are reported as *ParseError.
+func New(opts Opts) (*Processor, error) {
+	valid, err := parseOpts(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	delimiter := cfg.Delimiter
-	if delimiter == "" {
-		delimiter = "\t"
-	}
+	return &Processor{opts: valid}, nil
+}
 
-	return &Processor{
-		fields:        fieldSet,
-		delimiter:     delimiter,
-		separatedOnly: cfg.SeparatedOnly,
-	}, nil
+func NewProcessor(cfg *config.Cut) (*Processor, error) {
+	return New(Opts{
+		Fields:          cfg.Fields,
+		Characters:      cfg.Characters,
+		Bytes:           cfg.Bytes,
+		Delimiter:       cfg.Delimiter,
+		RegexDelimiter:  cfg.RegexDelimiter,
+		Whitespace:      cfg.Whitespace,
+		OutputDelimiter: cfg.OutputDelimiter,
+		SeparatedOnly:   cfg.SeparatedOnly,
+		NoSplit:         cfg.NoSplit,
+		Complement:      cfg.Complement,
+		KeepOrder:       cfg.KeepOrder,
+		CSV:             cfg.CSV,
+		ZeroTerminated:  cfg.ZeroTerminated,
+		JSONL:           cfg.JSONL,
+		To:              cfg.To,
+		Missing:         cfg.Missing,
+	})
 }
 
+// Process streams input to output through a buffered writer. Records
+// may be of any length. Read and write errors are returned as they happen.
+// A record that cannot be cut goes to OnRecordError and is skipped; a
+// *RecordsError counting them is returned once the input is done.
 func (p *Processor) Process(input io.Reader, output io.Writer) error {
-	scanner := bufio.NewScanner(input)
+	bw := bufio.NewWriterSize(output, outputBufferSize)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	if p.opts.csv {
+		if err := processCSV(input, bw, p.opts); err != nil {
+			_ = bw.Flush()
+			return err
+		}
+		return bw.Flush()
+	}
 
-		containsDelimiter := strings.Contains(line, p.delimiter)
+	reader := bufio.NewReaderSize(input, outputBufferSize)
 
-		if p.separatedOnly && !containsDelimiter {
-			continue
+	if p.opts.mode == modeFields && p.opts.literal != "" && p.opts.jsonl == nil {
+		if err := newFieldCutter(p.opts).process(reader, bw); err != nil {
+			_ = bw.Flush()
+			return err
 		}
+		return bw.Flush()
+	}
 
-		if !containsDelimiter {
-			_, _ = fmt.Fprintln(output, line)
-			continue
-		}
+	return p.processRecords(reader, bw)
+}
+
+// processRecords cuts record by record with cutLine, or cutJSONLine with
+// --jsonl, and flushes bw. It is the general engine that the field cutter
+// stands in for with a literal delimiter.
+func (p *Processor) processRecords(reader *bufio.Reader, bw *bufio.Writer) error {
+	cut := p.cutLine
+	if p.opts.jsonl != nil {
+		cut = p.cutJSONLine
+	}
 
-		fields := strings.Split(line, p.delimiter)
+	// A bad record is reported with its line number, and the records
+	// after it are still cut.
+	var recordErrs RecordsError
+	for num := 1; ; num++ {
+		record, readErr := reader.ReadString(p.opts.terminator)
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			_ = bw.Flush()
+			return readErr
+		}
+		if record == "" {
+			break
+		}
 
-		var outputFields []string
-		for i := 0; i < len(fields); i++ {
-			fieldIndex := i + 1
-			if _, ok := p.fields[fieldIndex]; ok {
-				outputFields = append(outputFields, fields[i])
+		line, ok, err := cut(p.trimTerminator(record))
+		if err != nil {
+			if err = p.recordError(bw, &recordErrs, fmt.Errorf("line %d: %w", num, err)); err != nil {
+				return err
+			}
+		} else if ok {
+			if _, err := bw.WriteString(line); err != nil {
+				return err
+			}
+			if err := bw.WriteByte(p.opts.terminator); err != nil {
+				return err
 			}
 		}
 
-		if len(outputFields) > 0 {
-			_, _ = fmt.Fprintln(output, strings.Join(outputFields, p.delimiter))
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if recordErrs.Count > 0 {
+		return &recordErrs
+	}
+	return nil
+}
+
+// recordError counts a bad record and hands it to OnRecordError, flushing
+// the output first so that the two come out in order.
+func (p *Processor) recordError(bw *bufio.Writer, recordErrs *RecordsError, err error) error {
+	recordErrs.Count++
+	if recordErrs.First == nil {
+		recordErrs.First = err
+	}
+	if p.OnRecordError == nil {
+		return nil
+	}
+
+	if flushErr := bw.Flush(); flushErr != nil {
+		return flushErr
+	}
+	p.OnRecordError(err)
+	return nil
+}
+
+// trimTerminator drops the record terminator. Newline-terminated records
+// also lose a trailing carriage return.
+func (p *Processor) trimTerminator(record string) string {
+	record = strings.TrimSuffix(record, string(p.opts.terminator))
+	if p.opts.terminator == '\n' {
+		record = strings.TrimSuffix(record, "\r")
+	}
+	return record
+}
+
+// cutLine returns the output for one input line, or false when the line
+// produces no output.
+func (p *Processor) cutLine(line string) (string, bool, error) {
+	if p.opts.mode != modeFields {
+		return selectPositions(line, p.opts.mode, p.opts.sel, p.opts.noSplit), true, nil
+	}
+
+	fields, containsDelimiter := p.opts.split(line)
+
+	if !containsDelimiter {
+		if len(fields) == 1 {
+			line = fields[0]
 		}
+		return line, !p.opts.separatedOnly, nil
+	}
+
+	if p.opts.sel.empty() {
+		return line, true, nil
+	}
+
+	outputFields := selectFields(fields, p.opts.sel)
+	if len(outputFields) == 0 {
+		return "", false, nil
 	}
 
-	return scanner.Err()
+	return strings.Join(outputFields, p.opts.outDelimiter), true, nil
 }
@@ -0,0 +1,42 @@
+package cut
+
+import (
+	"regexp"
+	"strings"
+)
+
+// splitFunc splits a line into fields. ok is false when the line contains
+// no delimiter at all; fields is then nil, or holds the line as the one
+// field it is printed as.
+type splitFunc func(line string) (fields []string, ok bool)
+
+func literalSplitter(delimiter string) splitFunc {
+	return func(line string) ([]string, bool) {
+		if !strings.Contains(line, delimiter) {
+			return nil, false
+		}
+		return strings.Split(line, delimiter), true
+	}
+}
+
+func regexSplitter(re *regexp.Regexp) splitFunc {
+	return func(line string) ([]string, bool) {
+		if re.FindStringIndex(line) == nil {
+			return nil, false
+		}
+		return re.Split(line, -1), true
+	}
+}
+
+// whitespaceSplitter splits on runs of blanks and ignores leading and
+// trailing ones, like awk does. A line with a single field has no
+// delimiter, but is still trimmed.
+func whitespaceSplitter() splitFunc {
+	return func(line string) ([]string, bool) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return []string{strings.TrimSpace(line)}, false
+		}
+		return fields, true
+	}
+}
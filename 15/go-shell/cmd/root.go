@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strings"
 	"wb-tech-l2/15/go-shell/internal/handler"
+	"wb-tech-l2/15/go-shell/internal/shell"
 
 	"github.com/spf13/cobra"
 )
@@ -13,25 +17,78 @@ var (
 	shortMsg = "Mini Unix shell implementation in Go"
 	longMsg  = `
 Go-shell is a mini Unix shell implementation that supports basic commands.
+Started without arguments it reads commands interactively, with line
+editing, history kept in ~/.go_shell_history and reverse-i-search (Ctrl+R).
+The prompt is taken from PS1. Ctrl+C drops the current line, Ctrl+D exits.
+Commands can be joined into pipelines, "ps | go-grep init | go-sort", and
+"set -o pipefail" makes a pipeline fail when any of its commands fails.
+Redirections >, >>, <, 2>, 2>&1, &> and <<EOF here-documents work for
+built-ins and programs alike.
+Commands are joined with ;, && and ||, and "!" negates a pipeline. Words
+follow POSIX quoting ('...', "...", \) and expand $VAR, ${VAR:-default},
+${#VAR} and $?. Unquoted *, ? and [...] match file names, and a pattern
+that matches nothing stays as it is. Variables are set with NAME=value
+and passed to programs with export; unset removes them.
+A command ending in & runs in the background. Interactively every
+pipeline gets its own process group and the terminal, Ctrl+Z stops it,
+and jobs, fg, bg and wait manage the jobs. Ctrl+C and Ctrl+\ interrupt
+the foreground job, never the shell itself. After Ctrl+C or Ctrl+Z the
+loop or list the job ran in stops too and the prompt comes back.
+"go-shell script.sh args..." runs a script, which may start with a
+"#!/path/to/go-shell" line; a script named like a command is run as
+./name. "go-shell -c 'commands' name args..." runs a string. Both see
+their arguments as $1, $2, "$@" and $#. In scripts "set -e" stops at
+the first failing command, "set -u" makes unset variables an error and
+"set -x" prints commands as they run.
+Control flow follows POSIX: if/elif/else/fi, while and until loops, for
+NAME in words, case with glob patterns, { ...; } groups, break and
+continue. Functions are defined with "name() { ...; }", get their
+arguments as $1, $2, ..., declare variables with local and end with
+return. Compound commands take redirections and can be piped.
 
 Built-in commands:
   echo    - display a line of text
   cd      - change the working directory
   pwd     - print working directory
-  kill    - send a signal to a process
-  ps      - report a snapshot of current processes
+  kill    - send a signal to processes or %jobs, kill -l lists signals
+  ps      - list processes like ps aux; -o pid,user,%cpu,... picks the
+            columns, --sort=-rss orders them and --tree nests children
+  set     - change shell options
+  export  - export variables to programs
+  unset   - remove variables
+  jobs    - list background and stopped jobs
+  fg, bg  - continue a job in the foreground or background
+  wait    - wait for jobs to finish
+  source  - run a file in the current shell, also "."
+  shift   - drop positional parameters
+  exit    - exit the shell with a status
+  test, [ - evaluate a condition: -f FILE, -n STR, A = B, N -lt M, ...
+  break, continue - leave a loop or go on with its next iteration
+  local   - declare variables local to a function
+  return  - return from a function or sourced file
+
+Any other command is looked up in PATH and run as a program.
 
 Examples:
+  go-shell
   go-shell echo "Hello World"
   go-shell cd /tmp
   go-shell pwd
   go-shell kill 1234
   go-shell ps
+  go-shell ps --sort=-%cpu -o pid,user,%cpu,args
+  go-shell ls -la /tmp
+  go-shell -c 'cd /tmp && pwd'
+  go-shell -c 'for f in *.go; do [ -s "$f" ] || echo "$f is empty"; done'
+  go-shell deploy.sh staging
 `
 )
 
 func init() {
 	rootCmd.PersistentFlags().BoolP("help", "h", false, "shows app usage")
+	rootCmd.Flags().StringP("command", "c", "", "runs the commands in the string")
+	// Everything after the command name belongs to the command.
+	rootCmd.Flags().SetInterspersed(false)
 }
 
 func setupCommandArgs(args []string) []string {
@@ -40,10 +97,49 @@ func setupCommandArgs(args []string) []string {
 	return commandArgs
 }
 
+// isScript reports whether the first argument names a file to run as a
+// script rather than a command. A name without a slash stays a command
+// when there is a built-in or a program in PATH by that name, so that
+// "go-shell pwd" runs pwd whatever the directory holds; "./pwd" runs the
+// file.
+func isScript(name string) bool {
+	if !strings.Contains(name, "/") {
+		if _, err := exec.LookPath(name); err == nil || handler.IsBuiltin(name) {
+			return false
+		}
+	}
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
 func runApp(cmd *cobra.Command, args []string) {
+	if cmd.Flags().Changed("command") {
+		text, _ := cmd.Flags().GetString("command")
+		sh := shell.New()
+		if len(args) > 0 {
+			sh.SetArgs(args[0], args[1:])
+		}
+		os.Exit(sh.Run(text))
+	}
+
+	if len(args) > 0 && isScript(args[0]) {
+		sh := shell.New()
+		sh.SetArgs(args[0], args[1:])
+		status, err := sh.RunFile(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(126)
+		}
+		os.Exit(status)
+	}
+
 	if len(args) == 0 {
-		_ = cmd.Help()
-		return
+		sh := shell.New()
+		if err := sh.RunInteractive(); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		os.Exit(sh.Status())
 	}
 
 	if err := handler.HandleCommand(
@@ -51,8 +147,11 @@ func runApp(cmd *cobra.Command, args []string) {
 		setupCommandArgs(args),
 		os.Stdout,
 	); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+		var statusErr *handler.StatusError
+		if !errors.As(err, &statusErr) {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		}
+		os.Exit(handler.ExitStatus(err))
 	}
 }
 
@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
 	"io"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"syscall"
 )
@@ -15,13 +15,24 @@ var validCommands = map[string]func(args []string, w io.Writer) error{
 	"pwd":  pwd,
 	"kill": kill,
 	"ps":   ps,
+	"test": test,
+	"[":    bracket,
 }
 
+// HandleCommand runs a built-in, or else the program of that name from
+// PATH. A program exiting with a non-zero status yields a *StatusError.
 func HandleCommand(command string, args []string, w io.Writer) error {
 	if c, ok := validCommands[command]; ok {
 		return c(args, w)
 	}
-	return fmt.Errorf("command %s: %w", command, errInvalidCommand)
+	return runExternal(command, args, w)
+}
+
+// IsBuiltin reports whether HandleCommand runs command itself rather than
+// a program.
+func IsBuiltin(command string) bool {
+	_, ok := validCommands[command]
+	return ok
 }
 
 func echo(args []string, w io.Writer) error {
@@ -40,46 +51,41 @@ func cd(args []string, _ io.Writer) error {
 	return os.Chdir(args[0])
 }
 
-func pwd(_ []string, w io.Writer) error {
-	dir, err := os.Getwd()
-	if err != nil {
-		return err
+// ResolveDir returns the directory that cd with args changes to from base,
+// args[0] or else the home directory, without changing to it. It fails as
+// cd would when that is not a directory. Subshells, which share the
+// process's working directory, keep their own this way.
+func ResolveDir(base string, args []string) (string, error) {
+	target := ""
+	if len(args) > 0 {
+		target = args[0]
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		target = home
 	}
-	_, err = w.Write([]byte(dir + "\n"))
-	return err
-}
 
-func kill(args []string, _ io.Writer) error {
-	if len(args) == 0 {
-		return fmt.Errorf("kill: missing operand")
+	dir := target
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
 	}
-
-	pid, err := strconv.Atoi(args[0])
+	info, err := os.Stat(dir)
 	if err != nil {
-		return fmt.Errorf("kill: invalid PID")
+		return "", &os.PathError{Op: "chdir", Path: target, Err: errors.Unwrap(err)}
 	}
-
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return err
+	if !info.IsDir() {
+		return "", &os.PathError{Op: "chdir", Path: target, Err: syscall.ENOTDIR}
 	}
-
-	return process.Signal(syscall.SIGTERM)
+	return filepath.Clean(dir), nil
 }
 
-func ps(_ []string, w io.Writer) error {
-	processes, err := os.ReadDir("/proc")
+func pwd(_ []string, w io.Writer) error {
+	dir, err := os.Getwd()
 	if err != nil {
 		return err
 	}
-
-	for _, process := range processes {
-		if _, err = strconv.Atoi(process.Name()); err == nil {
-			_, err = w.Write([]byte(process.Name() + "\n"))
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	_, err = w.Write([]byte(dir + "\n"))
+	return err
 }
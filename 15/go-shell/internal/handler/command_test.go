@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExternalCommands(t *testing.T) {
+	tests := []struct {
+		name           string
+		command        string
+		args           []string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"builtin", "echo", []string{"a", "b"}, 0, "a b\n"},
+		{"program output", "sh", []string{"-c", "echo from sh"}, 0, "from sh\n"},
+		{"exit status", "sh", []string{"-c", "exit 3"}, 3, ""},
+		{"killed by signal", "sh", []string{"-c", "kill -TERM $$"}, 128 + 15, ""},
+		{"not found", "go-shell-no-such-command", nil, 127, ""},
+		{"not executable", "/dev/null", nil, 126, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			err := HandleCommand(tt.command, tt.args, &output)
+
+			if status := ExitStatus(err); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%v)", tt.expectedStatus, status, err)
+			}
+			if output.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, output.String())
+			}
+		})
+	}
+}
+
+func TestIsBuiltin(t *testing.T) {
+	for name, expected := range map[string]bool{"cd": true, "[": true, "ls": false, "": false} {
+		if got := IsBuiltin(name); got != expected {
+			t.Errorf("Expected IsBuiltin(%q) to be %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	sh := func(script string) Stage { return Stage{Name: "sh", Args: []string{"-c", script}} }
+	cmd := func(name string, args ...string) Stage { return Stage{Name: name, Args: args} }
+	upper := Stage{Run: func(std Stdio) error {
+		data, err := io.ReadAll(std.In)
+		if err != nil {
+			return err
+		}
+		_, err = std.Out.Write(bytes.ToUpper(data))
+		return err
+	}}
+	fail := Stage{Run: func(Stdio) error { return &StatusError{Code: 5} }}
+	inRoot := sh("pwd")
+	inRoot.Dir = "/"
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		stages         []Stage
+		stdin          string
+		pipefail       bool
+		expectedStatus int
+		expectedOutput string
+		expectedErr    string
+	}{
+		{"programs", []Stage{sh("printf abc"), cmd("tr", "a-z", "A-Z")}, "", false, 0, "ABC", ""},
+		{"builtin into program", []Stage{cmd("echo", "hello"), cmd("tr", "a-z", "A-Z")}, "", false, 0, "HELLO\n", ""},
+		{"program into builtin", []Stage{cmd("yes"), cmd("echo", "done")}, "", false, 0, "done\n", ""},
+		{"builtins", []Stage{cmd("echo", "a"), cmd("echo", "b")}, "", false, 0, "b\n", ""},
+		{"stdin reaches the first stage", []Stage{cmd("cat"), cmd("tr", "x", "y")}, "xx\n", false, 0, "yy\n", ""},
+		{"long pipeline", []Stage{cmd("echo", "3 1 2"), cmd("tr", " ", "\n"), cmd("sort"), cmd("head", "-1")}, "", false, 0, "1\n", ""},
+		{"status of the last stage", []Stage{sh("exit 3"), cmd("true")}, "", false, 0, "", ""},
+		{"failing last stage", []Stage{cmd("true"), sh("exit 3")}, "", false, 3, "", ""},
+		{"pipefail", []Stage{sh("exit 3"), sh("exit 4"), cmd("true")}, "", true, 4, "", ""},
+		{"in-process stage", []Stage{sh("printf abc"), upper, cmd("tr", "B", "-")}, "", false, 0, "A-C", ""},
+		{"in-process stages", []Stage{cmd("echo", "x"), upper, upper}, "", false, 0, "X\n", ""},
+		{"failing in-process stage", []Stage{cmd("echo", "x"), fail}, "", false, 5, "", ""},
+		{"program in a directory", []Stage{inRoot, cmd("cat")}, "", false, 0, "/\n", ""},
+		{"cd stays in its stage", []Stage{cmd("cd", "/"), cmd("pwd")}, "", false, 0, wd + "\n", ""},
+		{"failing cd in a pipeline", []Stage{cmd("cd", "/go-shell-missing"), cmd("true")}, "", false, 0, "", "no such file"},
+		{"missing stage", []Stage{cmd("echo", "a"), cmd("go-shell-no-such-command"), cmd("cat")}, "", false, 0, "", "invalid command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output, errOutput bytes.Buffer
+			err := RunPipeline(tt.stages, strings.NewReader(tt.stdin), &output, &errOutput, tt.pipefail)
+
+			if status := ExitStatus(err); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%v)", tt.expectedStatus, status, err)
+			}
+			if output.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, output.String())
+			}
+			if !strings.Contains(errOutput.String(), tt.expectedErr) || (tt.expectedErr == "" && errOutput.Len() > 0) {
+				t.Errorf("Expected stderr with %q, got %q", tt.expectedErr, errOutput.String())
+			}
+		})
+	}
+}
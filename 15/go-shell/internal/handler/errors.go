@@ -1,7 +1,55 @@
 package handler
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
 
 var (
 	errInvalidCommand = errors.New("invalid command")
+	errBadDescriptor  = errors.New("bad file descriptor")
 )
+
+// StatusError reports that a program ran and exited with a non-zero
+// status. The program has already explained itself, so shells do not print
+// it.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Report prints err to w unless it only carries the exit status of a
+// program, and returns it.
+func Report(w io.Writer, err error) error {
+	var statusErr *StatusError
+	if err != nil && !errors.As(err, &statusErr) {
+		_, _ = fmt.Fprintln(w, err.Error())
+	}
+	return err
+}
+
+// ExitStatus maps the result of HandleCommand to a shell exit status:
+// 127 when the command is not found, 126 when it cannot be executed and 2
+// for a malformed test expression.
+func ExitStatus(err error) int {
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &statusErr):
+		return statusErr.Code
+	case errors.Is(err, errInvalidCommand):
+		return 127
+	case errors.Is(err, fs.ErrPermission):
+		return 126
+	case isTestSyntaxError(err):
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// runExternal runs a program found in PATH. It shares the shell's stdin
+// and stderr, so interactive programs work as usual.
+func runExternal(command string, args []string, w io.Writer) error {
+	cmd, err := startExternal(command, args, nil, "", nil, Stdio{In: os.Stdin, Out: w, Err: os.Stderr})
+	if err != nil {
+		return err
+	}
+	return waitExternal(cmd)
+}
+
+// startExternal starts a program in dir, or in the shell's directory when
+// dir is empty. A relative path to the program is taken from dir too.
+func startExternal(command string, args, env []string, dir string, attr *syscall.SysProcAttr, std Stdio) (*exec.Cmd, error) {
+	lookup := command
+	if dir != "" && strings.Contains(command, "/") && !filepath.IsAbs(command) {
+		lookup = filepath.Join(dir, command)
+	}
+	path, err := exec.LookPath(lookup)
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("command %s: %w", command, errInvalidCommand)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Args[0] = command
+	cmd.Env = env
+	cmd.Dir = dir
+	cmd.SysProcAttr = attr
+	cmd.Stdin = std.In
+	cmd.Stdout = std.Out
+	cmd.Stderr = std.Err
+
+	return cmd, cmd.Start()
+}
+
+func waitExternal(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &StatusError{Code: exitCode(exitErr.ProcessState)}
+	}
+	return err
+}
+
+// exitCode follows the shell convention of 128+N for a process killed by
+// signal N.
+func exitCode(state *os.ProcessState) int {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
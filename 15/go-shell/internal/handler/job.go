@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+type JobState int
+
+const (
+	JobRunning JobState = iota
+	JobStopped
+	JobDone
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "Running"
+	case JobStopped:
+		return "Stopped"
+	default:
+		return "Done"
+	}
+}
+
+// si_code values of SIGCHLD, from <signal.h>.
+const (
+	cldStopped   = 5
+	cldContinued = 6
+)
+
+// Job is a started pipeline. Its programs share the process group Pgid
+// when the pipeline was started with Setpgid.
+type Job struct {
+	Pgid int
+	Pids []int
+
+	pipefail bool
+	started  chan struct{} // closed once every stage has started
+
+	mu      sync.Mutex
+	changed *sync.Cond
+	states  []JobState
+	results []error
+	program []bool
+}
+
+func newJob(n int, pipefail bool) *Job {
+	j := &Job{
+		pipefail: pipefail,
+		started:  make(chan struct{}),
+		states:   make([]JobState, n),
+		results:  make([]error, n),
+		program:  make([]bool, n),
+	}
+	j.changed = sync.NewCond(&j.mu)
+	return j
+}
+
+func (j *Job) setState(i int, state JobState, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.states[i] = state
+	j.results[i] = err
+	j.changed.Broadcast()
+}
+
+// State is Done when every stage has finished, and Stopped when a program
+// is stopped and none is running. Built-ins cannot be stopped, so they do
+// not count.
+func (j *Job) State() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state()
+}
+
+func (j *Job) state() JobState {
+	done, stopped, running := true, false, false
+	for i, state := range j.states {
+		done = done && state == JobDone
+		stopped = stopped || state == JobStopped
+		running = running || (state == JobRunning && j.program[i])
+	}
+
+	switch {
+	case done:
+		return JobDone
+	case stopped && !running:
+		return JobStopped
+	default:
+		return JobRunning
+	}
+}
+
+// Wait blocks until the job is done or stopped and returns which.
+func (j *Job) Wait() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for {
+		if state := j.state(); state != JobRunning {
+			return state
+		}
+		j.changed.Wait()
+	}
+}
+
+// Err returns the result of a finished job: a *StatusError carrying the
+// status of the last stage or, with pipefail, of the last stage that
+// failed.
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := 0
+	for _, err := range j.results {
+		if code := ExitStatus(err); code != 0 || !j.pipefail {
+			status = code
+		}
+	}
+
+	if status != 0 {
+		return &StatusError{Code: status}
+	}
+	return nil
+}
+
+// Continue sends SIGCONT to the job's process group, or to each of its
+// programs when it has none. The job counts as running right away rather
+// than once the programs report back.
+func (j *Job) Continue() error {
+	if err := j.Signal(syscall.SIGCONT); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i, state := range j.states {
+		if state == JobStopped {
+			j.states[i] = JobRunning
+		}
+	}
+	return nil
+}
+
+// Signal sends sig to the job's programs. Those that have exited are
+// skipped.
+func (j *Job) Signal(sig syscall.Signal) error {
+	if j.Pgid != 0 {
+		return syscall.Kill(-j.Pgid, sig)
+	}
+
+	var errs []error
+	for _, pid := range j.Pids {
+		if err := syscall.Kill(pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watch follows program i through stops and continues until it exits.
+// waitid with WNOWAIT only peeks, so that exec.Cmd still reaps the
+// process and finishes copying its output.
+func (j *Job) watch(i int, cmd *exec.Cmd) {
+	pid := cmd.Process.Pid
+	for {
+		var info unix.Siginfo
+		err := unix.Waitid(unix.P_PID, pid, &info, unix.WEXITED|unix.WSTOPPED|unix.WCONTINUED|unix.WNOWAIT, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil || (info.Code != cldStopped && info.Code != cldContinued) {
+			break
+		}
+
+		// Consume the report that was peeked at. Its state may have
+		// changed since, so the consumed one is taken.
+		info = unix.Siginfo{}
+		_ = unix.Waitid(unix.P_PID, pid, &info, unix.WSTOPPED|unix.WCONTINUED|unix.WNOHANG, nil)
+		switch info.Code {
+		case cldStopped:
+			j.setState(i, JobStopped, nil)
+		case cldContinued:
+			j.setState(i, JobRunning, nil)
+		}
+	}
+
+	// A process group lives as long as its leader is not reaped, and the
+	// later stages still have to join it.
+	<-j.started
+	j.setState(i, JobDone, waitExternal(cmd))
+}
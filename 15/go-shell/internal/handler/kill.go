@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	errMissingOperand = errors.New("usage: kill [-s SIGNAL | -SIGNAL] PID | %JOB ... or kill -l [SIGNAL]")
+	errInvalidSignal  = errors.New("invalid signal specification")
+	errInvalidTarget  = errors.New("arguments must be process or job IDs")
+	errNoJobControl   = errors.New("no job control")
+)
+
+// JobResolver returns the processes of a %job spec. A negative number
+// stands for a process group.
+type JobResolver func(spec string) ([]int, error)
+
+func kill(args []string, w io.Writer) error {
+	return Kill(args, w, nil)
+}
+
+// Kill sends a signal, SIGTERM unless given as -SIGNAL, -N or -s SIGNAL,
+// to PIDs, process groups (negative PIDs) and jobs. kill -l lists the
+// signals, or translates between signal names and numbers.
+func Kill(args []string, w io.Writer, jobs JobResolver) error {
+	sig := syscall.SIGTERM
+	i := 0
+
+	if len(args) > 0 {
+		switch arg := args[0]; {
+		case arg == "-l" || arg == "-L":
+			return listSignals(args[1:], w)
+		case arg == "-s" || arg == "-n":
+			if len(args) < 2 {
+				return fmt.Errorf("kill: %s: %w", arg, errMissingOperand)
+			}
+			parsed, err := ParseSignal(args[1])
+			if err != nil {
+				return fmt.Errorf("kill: %w", err)
+			}
+			sig, i = parsed, 2
+		case arg == "--":
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			parsed, err := ParseSignal(arg[1:])
+			if err != nil {
+				return fmt.Errorf("kill: %w", err)
+			}
+			sig, i = parsed, 1
+		}
+	}
+	if i < len(args) && args[i] == "--" {
+		i++
+	}
+
+	targets := args[i:]
+	if len(targets) == 0 {
+		return fmt.Errorf("kill: %w", errMissingOperand)
+	}
+
+	var errs []error
+	for _, target := range targets {
+		pids, err := resolveTarget(target, jobs)
+		if err == nil {
+			for _, pid := range pids {
+				if err = syscall.Kill(pid, sig); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("kill: %s: %w", target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func resolveTarget(target string, jobs JobResolver) ([]int, error) {
+	if strings.HasPrefix(target, "%") {
+		if jobs == nil {
+			return nil, errNoJobControl
+		}
+		return jobs(target)
+	}
+
+	pid, err := strconv.Atoi(target)
+	if err != nil {
+		return nil, errInvalidTarget
+	}
+	return []int{pid}, nil
+}
+
+// ParseSignal parses a signal given by number or by name, with or without
+// the SIG prefix and in any case.
+func ParseSignal(spec string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		if sig := syscall.Signal(n); n == 0 || unix.SignalName(sig) != "" {
+			return sig, nil
+		}
+		return 0, fmt.Errorf("%s: %w", spec, errInvalidSignal)
+	}
+
+	name := strings.ToUpper(spec)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%s: %w", spec, errInvalidSignal)
+}
+
+// listSignals prints all signal names, or translates each argument: a
+// number to a name and a name to a number. Exit statuses above 128 stand
+// for the signal that killed a program.
+func listSignals(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		// Five to a row, like bash.
+		var b strings.Builder
+		for sig := syscall.Signal(1); sig < 32; sig++ {
+			sep := "\t"
+			if sig%5 == 0 || sig == 31 {
+				sep = "\n"
+			}
+			_, _ = fmt.Fprintf(&b, "%2d) %s%s", sig, unix.SignalName(sig), sep)
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	for _, arg := range args {
+		var line string
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n > 128 {
+				n -= 128
+			}
+			name := unix.SignalName(syscall.Signal(n))
+			if name == "" {
+				return fmt.Errorf("kill: %s: %w", arg, errInvalidSignal)
+			}
+			line = strings.TrimPrefix(name, "SIG")
+		} else {
+			sig, err := ParseSignal(arg)
+			if err != nil {
+				return fmt.Errorf("kill: %w", err)
+			}
+			line = strconv.Itoa(int(sig))
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
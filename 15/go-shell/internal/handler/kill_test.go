@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected syscall.Signal
+	}{
+		{"9", syscall.SIGKILL},
+		{"0", 0},
+		{"KILL", syscall.SIGKILL},
+		{"SIGKILL", syscall.SIGKILL},
+		{"hup", syscall.SIGHUP},
+		{"sigusr1", syscall.SIGUSR1},
+	}
+
+	for _, tt := range tests {
+		sig, err := ParseSignal(tt.spec)
+		if err != nil || sig != tt.expected {
+			t.Errorf("Expected %v for %q, got %v (%v)", tt.expected, tt.spec, sig, err)
+		}
+	}
+
+	for _, spec := range []string{"FOO", "99", "-1", ""} {
+		if _, err := ParseSignal(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestKillList(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-l", "9"}, "KILL\n"},
+		{[]string{"-l", "130", "term"}, "INT\n15\n"},
+		{[]string{"-L", "SIGSTOP"}, "19\n"},
+	}
+
+	for _, tt := range tests {
+		var output bytes.Buffer
+		if err := Kill(tt.args, &output, nil); err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tt.args, err)
+		}
+		if output.String() != tt.expected {
+			t.Errorf("Expected %q for %v, got %q", tt.expected, tt.args, output.String())
+		}
+	}
+
+	var output bytes.Buffer
+	if err := Kill([]string{"-l"}, &output, nil); err != nil || !strings.Contains(output.String(), " 9) SIGKILL\t10) SIGUSR1\n") {
+		t.Errorf("Expected a table of signals, got %q (%v)", output.String(), err)
+	}
+}
+
+func TestKill(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     func(pid string) []string
+		expected syscall.Signal
+	}{
+		{"default", func(pid string) []string { return []string{pid} }, syscall.SIGTERM},
+		{"number", func(pid string) []string { return []string{"-9", pid} }, syscall.SIGKILL},
+		{"name", func(pid string) []string { return []string{"-INT", pid} }, syscall.SIGINT},
+		{"-s", func(pid string) []string { return []string{"-s", "hup", "--", pid} }, syscall.SIGHUP},
+		{"job", func(string) []string { return []string{"-USR1", "%1"} }, syscall.SIGUSR1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("sleep", "5")
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			jobs := func(spec string) ([]int, error) { return []int{cmd.Process.Pid}, nil }
+
+			if err := Kill(tt.args(strconv.Itoa(cmd.Process.Pid)), nil, jobs); err != nil {
+				_ = cmd.Process.Kill()
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_ = cmd.Wait()
+
+			if status := exitCode(cmd.ProcessState); status != 128+int(tt.expected) {
+				t.Errorf("Expected status %d, got %d", 128+int(tt.expected), status)
+			}
+		})
+	}
+}
+
+func TestKillErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "usage"},
+		{[]string{"-9"}, "usage"},
+		{[]string{"-s"}, "usage"},
+		{[]string{"-FOO", "1"}, "FOO: invalid signal"},
+		{[]string{"abc"}, "abc: arguments must be"},
+		{[]string{"%1"}, "%1: no job control"},
+	}
+
+	for _, tt := range tests {
+		err := Kill(tt.args, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected error with %q for %v, got %v", tt.expected, tt.args, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Stage is one command of a pipeline. A stage with Run set is run by
+// calling it, on a goroutine like a built-in; shells use that for their
+// functions, compound commands and own built-ins.
+type Stage struct {
+	Name      string
+	Args      []string
+	Redirects []Redirect
+	Env       []string // environment of a program, nil for the shell's own
+	Dir       string   // working directory of a program, "" for the shell's
+	Run       func(std Stdio) error
+}
+
+// Options control how StartPipeline runs the stages.
+type Options struct {
+	Pipefail bool
+
+	// Setpgid puts the programs into a process group of their own. With
+	// Foreground that group is also given the terminal Tty.
+	Setpgid    bool
+	Foreground bool
+	Tty        int
+}
+
+// RunPipeline runs all stages concurrently and waits for them, each one's
+// output feeding the next one's input. The returned error is a
+// *StatusError as described for Job.Err.
+func RunPipeline(stages []Stage, stdin io.Reader, stdout, stderr io.Writer, pipefail bool) error {
+	job, err := StartPipeline(stages, Stdio{In: stdin, Out: stdout, Err: stderr}, Options{Pipefail: pipefail})
+	if err != nil {
+		return err
+	}
+	job.Wait()
+	return job.Err()
+}
+
+// StartPipeline starts all stages and returns without waiting for them.
+// Programs are connected with OS pipes; two neighbouring built-ins, which
+// run on goroutines, share an io.Pipe instead. Every stage reports its own
+// errors to its stderr, after redirections.
+func StartPipeline(stages []Stage, std Stdio, opts Options) (*Job, error) {
+	stderr := std.Err
+	if _, ok := stderr.(*os.File); !ok {
+		// All stages share stderr, and only files are safe for that.
+		stderr = &syncWriter{w: stderr}
+	}
+
+	n := len(stages)
+	ins := make([]io.Reader, n)
+	outs := make([]io.Writer, n)
+	ins[0], outs[n-1] = std.In, std.Out
+
+	for i := 0; i < n-1; i++ {
+		r, w, err := newPipe(stages[i].inProcess() && stages[i+1].inProcess())
+		if err != nil {
+			for j := 0; j < i; j++ {
+				_ = outs[j].(io.Closer).Close()
+				_ = ins[j+1].(io.Closer).Close()
+			}
+			return nil, err
+		}
+		outs[i], ins[i+1] = w, r
+	}
+
+	// release closes the shell's own copies of the pipe ends of stage i,
+	// so that its neighbours see EOF or a broken pipe once it is done.
+	release := func(i int) {
+		if i > 0 {
+			_ = ins[i].(io.Closer).Close()
+		}
+		if i < n-1 {
+			_ = outs[i].(io.Closer).Close()
+		}
+	}
+
+	// Stages start one after another, as the first program founds the
+	// process group that the others join.
+	job := newJob(n, opts.Pipefail)
+	for i, stage := range stages {
+		job.start(i, stage, Stdio{In: ins[i], Out: outs[i], Err: stderr}, func() { release(i) }, opts)
+	}
+	close(job.started)
+
+	return job, nil
+}
+
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (s Stage) inProcess() bool {
+	_, ok := validCommands[s.Name]
+	return ok || s.Run != nil
+}
+
+func newPipe(inProcess bool) (io.ReadCloser, io.WriteCloser, error) {
+	if inProcess {
+		r, w := io.Pipe()
+		return r, w, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, w, nil
+}
+
+// start starts stage i and calls release as soon as its pipe ends are no
+// longer needed by the shell: right after a program has started, or when
+// a built-in returns. A stage without a name only redirects.
+func (j *Job) start(i int, stage Stage, std Stdio, release func(), opts Options) {
+	std, err := stage.Open(std)
+	if err != nil {
+		release()
+		j.setState(i, JobDone, Report(std.Err, err))
+		return
+	}
+
+	if c, ok := validCommands[stage.Name]; ok || stage.Name == "" || stage.Run != nil {
+		go func() {
+			defer func() { _ = std.Close() }()
+			defer release()
+			if stage.Name == "" && stage.Run == nil {
+				j.setState(i, JobDone, nil)
+				return
+			}
+
+			var err error
+			switch {
+			case stage.Run != nil:
+				err = stage.Run(std)
+			case stage.Name == "cd" && len(j.states) > 1:
+				// Like a subshell, a stage of a pipeline cannot change
+				// the shell's directory.
+				_, err = ResolveDir(".", stage.Args)
+			default:
+				err = c(stage.Args, std.Out)
+			}
+			if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, syscall.EPIPE) {
+				// A program would have died of SIGPIPE, quietly.
+				err = &StatusError{Code: 128 + int(syscall.SIGPIPE)}
+			}
+			j.setState(i, JobDone, Report(std.Err, err))
+		}()
+		return
+	}
+
+	var attr *syscall.SysProcAttr
+	if opts.Setpgid {
+		attr = &syscall.SysProcAttr{
+			Setpgid:    true,
+			Pgid:       j.Pgid,
+			Foreground: opts.Foreground && j.Pgid == 0,
+			Ctty:       opts.Tty,
+		}
+	}
+
+	cmd, err := startExternal(stage.Name, stage.Args, stage.Env, stage.Dir, attr, std)
+	release()
+	if err != nil {
+		_ = std.Close()
+		j.setState(i, JobDone, Report(std.Err, err))
+		return
+	}
+
+	j.program[i] = true
+	j.Pids = append(j.Pids, cmd.Process.Pid)
+	if opts.Setpgid && j.Pgid == 0 {
+		j.Pgid = cmd.Process.Pid
+	}
+
+	go func() {
+		defer func() { _ = std.Close() }()
+		j.watch(i, cmd)
+	}()
+}
@@ -0,0 +1,433 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"wb-tech-l2/15/go-shell/internal/procfs"
+)
+
+var (
+	errUnknownOption = errors.New("unknown option")
+	errUnknownColumn = errors.New("unknown column")
+)
+
+const defaultPsColumns = "user,pid,%cpu,%mem,vsz,rss,tty,stat,start,time,args"
+
+// psRow is a process with the values derived for its columns.
+type psRow struct {
+	procfs.Process
+	user     string
+	cpu, mem float64
+	start    time.Time
+	started  string // start formatted for the START column
+	depth    int    // in the --tree view
+}
+
+type psColumn struct {
+	header  string
+	right   bool // numbers are right-aligned
+	value   func(r *psRow) string
+	compare func(a, b *psRow) int
+}
+
+func intColumn(header string, field func(r *psRow) int64) psColumn {
+	return psColumn{
+		header:  header,
+		right:   true,
+		value:   func(r *psRow) string { return strconv.FormatInt(field(r), 10) },
+		compare: func(a, b *psRow) int { return cmp.Compare(field(a), field(b)) },
+	}
+}
+
+func percentColumn(header string, field func(r *psRow) float64) psColumn {
+	return psColumn{
+		header:  header,
+		right:   true,
+		value:   func(r *psRow) string { return strconv.FormatFloat(field(r), 'f', 1, 64) },
+		compare: func(a, b *psRow) int { return cmp.Compare(field(a), field(b)) },
+	}
+}
+
+func stringColumn(header string, field func(r *psRow) string) psColumn {
+	return psColumn{
+		header:  header,
+		value:   field,
+		compare: func(a, b *psRow) int { return strings.Compare(field(a), field(b)) },
+	}
+}
+
+var psColumns = map[string]psColumn{
+	"pid":  intColumn("PID", func(r *psRow) int64 { return int64(r.PID) }),
+	"ppid": intColumn("PPID", func(r *psRow) int64 { return int64(r.PPID) }),
+	"pgid": intColumn("PGID", func(r *psRow) int64 { return int64(r.PGID) }),
+	"sid":  intColumn("SID", func(r *psRow) int64 { return int64(r.SID) }),
+	"uid":  intColumn("UID", func(r *psRow) int64 { return int64(r.UID) }),
+	"ni":   intColumn("NI", func(r *psRow) int64 { return int64(r.Nice) }),
+	"nlwp": intColumn("NLWP", func(r *psRow) int64 { return int64(r.Threads) }),
+	"vsz":  intColumn("VSZ", func(r *psRow) int64 { return int64(r.VSZ) }),
+	"rss":  intColumn("RSS", func(r *psRow) int64 { return int64(r.RSS) }),
+	"%cpu": percentColumn("%CPU", func(r *psRow) float64 { return r.cpu }),
+	"%mem": percentColumn("%MEM", func(r *psRow) float64 { return r.mem }),
+	"user": stringColumn("USER", func(r *psRow) string { return r.user }),
+	"tty":  stringColumn("TTY", func(r *psRow) string { return procfs.TTYName(r.TTY) }),
+	"s":    stringColumn("S", func(r *psRow) string { return r.State }),
+	"stat": stringColumn("STAT", stat),
+	"comm": stringColumn("COMMAND", func(r *psRow) string { return treePrefix(r.depth) + r.Comm }),
+	"args": stringColumn("COMMAND", func(r *psRow) string { return treePrefix(r.depth) + commandLine(r) }),
+	"start": {
+		header:  "START",
+		value:   func(r *psRow) string { return r.started },
+		compare: func(a, b *psRow) int { return a.start.Compare(b.start) },
+	},
+	"time": {
+		header:  "TIME",
+		right:   true,
+		value:   func(r *psRow) string { return cpuTime(r.UTime + r.STime) },
+		compare: func(a, b *psRow) int { return cmp.Compare(a.UTime+a.STime, b.UTime+b.STime) },
+	},
+}
+
+var psAliases = map[string]string{
+	"pcpu":    "%cpu",
+	"pmem":    "%mem",
+	"cmd":     "args",
+	"command": "args",
+	"ucomm":   "comm",
+	"state":   "s",
+	"thcount": "nlwp",
+	"cputime": "time",
+	"lstart":  "start",
+}
+
+// stat is the state followed by the BSD flags of ps aux: < and N for
+// raised and lowered priority, s for a session leader, l for several
+// threads and + for the foreground process group of the terminal.
+func stat(r *psRow) string {
+	s := r.State
+	switch {
+	case r.Nice < 0:
+		s += "<"
+	case r.Nice > 0:
+		s += "N"
+	}
+	if r.SID == r.PID {
+		s += "s"
+	}
+	if r.Threads > 1 {
+		s += "l"
+	}
+	if r.TPGID == r.PGID && r.TTY != 0 {
+		s += "+"
+	}
+	return s
+}
+
+// commandLine falls back to [comm] for kernel threads. Control
+// characters such as newlines in arguments are shown as ?, so that every
+// process stays on one line.
+func commandLine(r *psRow) string {
+	if len(r.Cmdline) == 0 {
+		return "[" + r.Comm + "]"
+	}
+	return strings.Map(func(c rune) rune {
+		if c < ' ' || c == 0x7f {
+			return '?'
+		}
+		return c
+	}, strings.Join(r.Cmdline, " "))
+}
+
+func treePrefix(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	return strings.Repeat("    ", depth-1) + " \\_ "
+}
+
+func cpuTime(ticks uint64) string {
+	seconds := ticks / procfs.ClockTicks
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+type psField struct {
+	column psColumn
+	header string
+}
+
+type psSortKey struct {
+	column psColumn
+	desc   bool
+}
+
+type psOptions struct {
+	fields []psField
+	sort   []psSortKey
+	tree   bool
+}
+
+// psCommand is ps with its sources of processes, time and user names.
+type psCommand struct {
+	fs         procfs.FS
+	now        time.Time
+	lookupUser func(uid int) string
+}
+
+func newPsCommand(fs procfs.FS) *psCommand {
+	names := make(map[int]string)
+	return &psCommand{
+		fs:  fs,
+		now: time.Now(),
+		lookupUser: func(uid int) string {
+			if name, ok := names[uid]; ok {
+				return name
+			}
+			name := strconv.Itoa(uid)
+			if u, err := user.LookupId(name); err == nil {
+				name = u.Username
+			}
+			names[uid] = name
+			return name
+		},
+	}
+}
+
+func ps(args []string, w io.Writer) error {
+	return Ps(args, w, procfs.DefaultRoot)
+}
+
+// Ps lists all processes like ps aux, reading them from the proc
+// filesystem mounted at root. -o selects columns, --sort orders by them
+// (a leading - reverses) and --tree shows children under their parents.
+func Ps(args []string, w io.Writer, root string) error {
+	return newPsCommand(procfs.FS{Root: root}).run(args, w)
+}
+
+func (c *psCommand) run(args []string, w io.Writer) error {
+	opts, err := parsePsArgs(args)
+	if err != nil {
+		return fmt.Errorf("ps: %w", err)
+	}
+
+	processes, err := c.fs.Processes()
+	if err != nil {
+		return fmt.Errorf("ps: %w", err)
+	}
+	system, err := c.fs.System()
+	if err != nil {
+		return fmt.Errorf("ps: %w", err)
+	}
+
+	rows := make([]*psRow, len(processes))
+	for i, p := range processes {
+		rows[i] = c.row(p, system)
+	}
+
+	slices.SortStableFunc(rows, func(a, b *psRow) int {
+		for _, key := range opts.sort {
+			if n := key.column.compare(a, b); n != 0 {
+				if key.desc {
+					return -n
+				}
+				return n
+			}
+		}
+		return 0
+	})
+	if opts.tree {
+		rows = tree(rows)
+	}
+
+	return writeTable(w, opts.fields, rows)
+}
+
+func (c *psCommand) row(p procfs.Process, system procfs.System) *psRow {
+	r := &psRow{Process: p, user: c.lookupUser(p.UID)}
+
+	started := time.Duration(p.StartTime) * time.Second / procfs.ClockTicks
+	r.start = system.BootTime.Add(started)
+	r.started = c.formatStart(r.start)
+	if elapsed := (system.Uptime - started).Seconds(); elapsed > 0 {
+		r.cpu = float64(p.UTime+p.STime) / procfs.ClockTicks / elapsed * 100
+	}
+	if system.MemTotal > 0 {
+		r.mem = float64(p.RSS) / float64(system.MemTotal) * 100
+	}
+
+	return r
+}
+
+// formatStart shows the time for processes started in the last day, the
+// date for this year and the year before that.
+func (c *psCommand) formatStart(start time.Time) string {
+	start = start.In(c.now.Location())
+	switch {
+	case c.now.Sub(start) < 24*time.Hour:
+		return start.Format("15:04")
+	case start.Year() == c.now.Year():
+		return start.Format("Jan02")
+	}
+	return start.Format("2006")
+}
+
+// tree reorders sorted rows depth first, so that every process follows
+// its parent. Processes whose parent is not listed are roots.
+func tree(rows []*psRow) []*psRow {
+	present := make(map[int]bool, len(rows))
+	for _, r := range rows {
+		present[r.PID] = true
+	}
+
+	children := make(map[int][]*psRow)
+	var roots []*psRow
+	for _, r := range rows {
+		if present[r.PPID] && r.PPID != r.PID {
+			children[r.PPID] = append(children[r.PPID], r)
+		} else {
+			roots = append(roots, r)
+		}
+	}
+
+	ordered := make([]*psRow, 0, len(rows))
+	var visit func(r *psRow, depth int)
+	visit = func(r *psRow, depth int) {
+		r.depth = depth
+		ordered = append(ordered, r)
+		for _, child := range children[r.PID] {
+			visit(child, depth+1)
+		}
+	}
+	for _, r := range roots {
+		visit(r, 0)
+	}
+
+	return ordered
+}
+
+func writeTable(w io.Writer, fields []psField, rows []*psRow) error {
+	table := make([][]string, 0, len(rows)+1)
+
+	header := make([]string, len(fields))
+	showHeader := false
+	for i, f := range fields {
+		header[i] = f.header
+		showHeader = showHeader || f.header != ""
+	}
+	if showHeader {
+		table = append(table, header)
+	}
+
+	for _, r := range rows {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			cells[i] = f.column.value(r)
+		}
+		table = append(table, cells)
+	}
+
+	widths := make([]int, len(fields))
+	for _, cells := range table {
+		for i, cell := range cells {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	var b strings.Builder
+	for _, cells := range table {
+		line := make([]string, len(cells))
+		for i, cell := range cells {
+			pad := strings.Repeat(" ", widths[i]-len(cell))
+			if fields[i].column.right {
+				line[i] = pad + cell
+			} else {
+				line[i] = cell + pad
+			}
+		}
+		b.WriteString(strings.TrimRight(strings.Join(line, " "), " ") + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func parsePsArgs(args []string) (psOptions, error) {
+	var opts psOptions
+	var format, sortKeys []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+
+		switch {
+		case arg == "aux" || arg == "ax" || arg == "-e" || arg == "-A":
+			// Every process is always listed.
+		case arg == "--tree" || arg == "--forest":
+			opts.tree = true
+		case arg == "-o" || arg == "--format" || arg == "--sort":
+			if i+1 == len(args) {
+				return opts, fmt.Errorf("%s: missing argument", arg)
+			}
+			i++
+			if arg == "--sort" {
+				sortKeys = append(sortKeys, args[i])
+			} else {
+				format = append(format, args[i])
+			}
+		case strings.HasPrefix(arg, "-o") && len(arg) > 2:
+			format = append(format, arg[2:])
+		case hasValue && name == "--format":
+			format = append(format, value)
+		case hasValue && name == "--sort":
+			sortKeys = append(sortKeys, value)
+		default:
+			return opts, fmt.Errorf("%s: %w", arg, errUnknownOption)
+		}
+	}
+
+	if len(format) == 0 {
+		format = []string{defaultPsColumns}
+	}
+	for _, spec := range strings.Split(strings.Join(format, ","), ",") {
+		name, header, hasHeader := strings.Cut(spec, "=")
+		column, err := lookupPsColumn(name)
+		if err != nil {
+			return opts, err
+		}
+		if !hasHeader {
+			header = column.header
+		}
+		opts.fields = append(opts.fields, psField{column: column, header: header})
+	}
+
+	if len(sortKeys) == 0 {
+		sortKeys = []string{"pid"}
+	}
+	for _, spec := range strings.Split(strings.Join(sortKeys, ","), ",") {
+		desc := strings.HasPrefix(spec, "-")
+		column, err := lookupPsColumn(strings.TrimLeft(spec, "+-"))
+		if err != nil {
+			return opts, err
+		}
+		opts.sort = append(opts.sort, psSortKey{column: column, desc: desc})
+	}
+
+	return opts, nil
+}
+
+func lookupPsColumn(name string) (psColumn, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := psAliases[name]; ok {
+		name = alias
+	}
+	column, ok := psColumns[name]
+	if !ok {
+		return column, fmt.Errorf("%s: %w", name, errUnknownColumn)
+	}
+	return column, nil
+}
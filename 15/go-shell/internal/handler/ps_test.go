@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+	"wb-tech-l2/15/go-shell/internal/procfs"
+)
+
+func newTestPs() *psCommand {
+	names := map[int]string{0: "root", 1000: "alice"}
+	return &psCommand{
+		fs:  procfs.FS{Root: "../procfs/testdata/proc"},
+		now: time.Unix(1700010000, 0).UTC(),
+		lookupUser: func(uid int) string {
+			if name, ok := names[uid]; ok {
+				return name
+			}
+			return strconv.Itoa(uid)
+		},
+	}
+}
+
+func TestPs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "aux",
+			args: []string{"aux"},
+			expected: "" +
+				"USER  PID %CPU %MEM    VSZ    RSS TTY   STAT START TIME COMMAND\n" +
+				"root    1  0.1  0.1 170000  12000 ?     Ss   22:13 0:08 /sbin/init splash\n" +
+				"root    2  0.0  0.0      0      0 ?     S    22:13 0:00 [kthreadd]\n" +
+				"alice 100  0.0  0.0   9000   4800 pts/0 Ss   23:36 0:02 -bash\n" +
+				"alice 200  0.0  0.0   8000   1000 pts/0 S+   00:58 0:00 sleep 100\n" +
+				"alice 201  9.0  1.5 500000 240000 pts/0 RNl  00:43 1:30 ./prog --flag\n",
+		},
+		{
+			name: "columns",
+			args: []string{"-o", "pid,ppid,stat,comm"},
+			expected: "" +
+				"PID PPID STAT COMMAND\n" +
+				"  1    0 Ss   systemd\n" +
+				"  2    0 S    kthreadd\n" +
+				"100    1 Ss   bash\n" +
+				"200  100 S+   sleep\n" +
+				"201  100 RNl  my (odd) prog\n",
+		},
+		{
+			name: "headers",
+			args: []string{"-o", "pid=ID", "-ouser="},
+			expected: "" +
+				" ID\n" +
+				"  1 root\n" +
+				"  2 root\n" +
+				"100 alice\n" +
+				"200 alice\n" +
+				"201 alice\n",
+		},
+		{
+			name: "no headers",
+			args: []string{"-o", "pid=,comm="},
+			expected: "" +
+				"  1 systemd\n" +
+				"  2 kthreadd\n" +
+				"100 bash\n" +
+				"200 sleep\n" +
+				"201 my (odd) prog\n",
+		},
+		{
+			name: "sort",
+			args: []string{"--sort=-rss,pid", "-o", "pid,rss,%mem"},
+			expected: "" +
+				"PID    RSS %MEM\n" +
+				"201 240000  1.5\n" +
+				"  1  12000  0.1\n" +
+				"100   4800  0.0\n" +
+				"200   1000  0.0\n" +
+				"  2      0  0.0\n",
+		},
+		{
+			name: "sort by user",
+			args: []string{"--sort", "user,-pid", "--format=user,pid,time"},
+			expected: "" +
+				"USER  PID TIME\n" +
+				"alice 201 1:30\n" +
+				"alice 200 0:00\n" +
+				"alice 100 0:02\n" +
+				"root    2 0:00\n" +
+				"root    1 0:08\n",
+		},
+		{
+			name: "tree",
+			args: []string{"--tree", "-o", "pid,args"},
+			expected: "" +
+				"PID COMMAND\n" +
+				"  1 /sbin/init splash\n" +
+				"100  \\_ -bash\n" +
+				"200      \\_ sleep 100\n" +
+				"201      \\_ ./prog --flag\n" +
+				"  2 [kthreadd]\n",
+		},
+		{
+			name: "tree sorted",
+			args: []string{"--forest", "--sort=-pid", "-o", "pid,comm"},
+			expected: "" +
+				"PID COMMAND\n" +
+				"  2 kthreadd\n" +
+				"  1 systemd\n" +
+				"100  \\_ bash\n" +
+				"201      \\_ my (odd) prog\n" +
+				"200      \\_ sleep\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := newTestPs().run(tt.args, &output); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestPsErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected error
+	}{
+		{[]string{"-x"}, errUnknownOption},
+		{[]string{"-o", "pid,bogus"}, errUnknownColumn},
+		{[]string{"--sort=-bogus"}, errUnknownColumn},
+	}
+
+	for _, tt := range tests {
+		err := newTestPs().run(tt.args, &bytes.Buffer{})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("Expected %v for %v, got %v", tt.expected, tt.args, err)
+		}
+	}
+
+	if err := newTestPs().run([]string{"-o"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for -o without a list")
+	}
+}
+
+func TestPsRoot(t *testing.T) {
+	var output bytes.Buffer
+	if err := Ps([]string{"-o", "pid,ppid,comm"}, &output, "../procfs/testdata/proc"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "" +
+		"PID PPID COMMAND\n" +
+		"  1    0 systemd\n" +
+		"  2    0 kthreadd\n" +
+		"100    1 bash\n" +
+		"200  100 sleep\n" +
+		"201  100 my (odd) prog\n"
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+
+	if err := Ps(nil, &bytes.Buffer{}, t.TempDir()); err == nil {
+		t.Error("Expected an error for a root without a proc filesystem")
+	}
+}
+
+func TestFormatStart(t *testing.T) {
+	c := newTestPs()
+	tests := []struct {
+		start    time.Time
+		expected string
+	}{
+		{c.now.Add(-time.Hour), "00:00"},
+		{c.now.Add(-48 * time.Hour), "Nov13"},
+		{c.now.AddDate(-1, 0, 0), "2022"},
+	}
+
+	for _, tt := range tests {
+		if s := c.formatStart(tt.start); s != tt.expected {
+			t.Errorf("Expected %q for %v, got %q", tt.expected, tt.start, s)
+		}
+	}
+}
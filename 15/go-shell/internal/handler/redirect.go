@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type RedirectKind int
+
+const (
+	RedirectInput   RedirectKind = iota // [n]<file
+	RedirectOutput                      // [n]>file
+	RedirectAppend                      // [n]>>file
+	RedirectDup                         // [n]>&m, [n]<&m
+	RedirectHereDoc                     // [n]<<DELIM, Target holds the body
+)
+
+// Redirect changes one file descriptor of a stage before it runs.
+type Redirect struct {
+	Fd     int
+	Kind   RedirectKind
+	Target string
+}
+
+// Stdio holds the standard streams of a stage together with the files
+// opened for its redirections.
+type Stdio struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+
+	files []*os.File
+}
+
+// Close closes the files opened by Open.
+func (std *Stdio) Close() error {
+	var errs []error
+	for _, f := range std.files {
+		errs = append(errs, f.Close())
+	}
+	std.files = nil
+	return errors.Join(errs...)
+}
+
+// Open applies the stage's redirections, left to right, on top of std.
+// On error every file it opened is closed again and std is returned as
+// it was.
+func (s Stage) Open(std Stdio) (Stdio, error) {
+	result := std
+	result.files = nil
+
+	for _, r := range s.Redirects {
+		if err := result.apply(r); err != nil {
+			_ = result.Close()
+			return std, err
+		}
+	}
+
+	result.files = append(std.files, result.files...)
+	return result, nil
+}
+
+func (std *Stdio) apply(r Redirect) error {
+	if r.Fd < 0 || r.Fd > 2 {
+		return fmt.Errorf("%d: %w", r.Fd, errBadDescriptor)
+	}
+
+	switch r.Kind {
+	case RedirectInput:
+		return std.openFile(r.Fd, r.Target, os.O_RDONLY)
+	case RedirectOutput:
+		return std.openFile(r.Fd, r.Target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	case RedirectAppend:
+		return std.openFile(r.Fd, r.Target, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	case RedirectHereDoc:
+		if r.Fd != 0 {
+			return fmt.Errorf("%d: %w", r.Fd, errBadDescriptor)
+		}
+		std.In = strings.NewReader(r.Target)
+		return nil
+	case RedirectDup:
+		target, err := strconv.Atoi(r.Target)
+		switch {
+		case err != nil || target < 0 || target > 2 || (r.Fd == 0) != (target == 0):
+			return fmt.Errorf("%s: %w", r.Target, errBadDescriptor)
+		case target > 0:
+			std.setWriter(r.Fd, std.writer(target))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown redirection %d", r.Kind)
+}
+
+func (std *Stdio) openFile(fd int, name string, flag int) error {
+	if (fd == 0) != (flag == os.O_RDONLY) {
+		return fmt.Errorf("%d: %w", fd, errBadDescriptor)
+	}
+
+	file, err := os.OpenFile(name, flag, 0o666)
+	if err != nil {
+		return err
+	}
+	std.files = append(std.files, file)
+
+	if fd == 0 {
+		std.In = file
+	} else {
+		std.setWriter(fd, file)
+	}
+	return nil
+}
+
+func (std *Stdio) writer(fd int) io.Writer {
+	if fd == 1 {
+		return std.Out
+	}
+	return std.Err
+}
+
+func (std *Stdio) setWriter(fd int, w io.Writer) {
+	if fd == 1 {
+		std.Out = w
+	} else {
+		std.Err = w
+	}
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// Malformed expressions make test exit with status 2 rather than 1.
+var (
+	errMissingBracket   = errors.New("missing `]'")
+	errIntegerExpected  = errors.New("integer expression expected")
+	errUnexpectedArg    = errors.New("unexpected argument")
+	errArgumentExpected = errors.New("argument expected")
+	errParenExpected    = errors.New("`)' expected")
+)
+
+func isTestSyntaxError(err error) bool {
+	for _, target := range []error{errMissingBracket, errIntegerExpected, errUnexpectedArg, errArgumentExpected, errParenExpected} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// test evaluates a conditional expression and succeeds when it is true:
+// file tests like -f and -d, string tests like -z and =, integer
+// comparisons like -lt, combined with !, -a, -o and parentheses.
+func test(args []string, _ io.Writer) error {
+	return testStatus("test", args)
+}
+
+// bracket is test spelled "[ expression ]".
+func bracket(args []string, _ io.Writer) error {
+	if len(args) == 0 || args[len(args)-1] != "]" {
+		return fmt.Errorf("[: %w", errMissingBracket)
+	}
+	return testStatus("[", args[:len(args)-1])
+}
+
+func testStatus(name string, args []string) error {
+	ok, err := evalTest(args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if !ok {
+		return &StatusError{Code: 1}
+	}
+	return nil
+}
+
+// evalTest parses the expression by recursive descent. Where an argument
+// could be an operator or an operand, as in "test -n = x", a binary
+// operator in the middle wins, as POSIX has it for three arguments.
+func evalTest(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	t := &testExpr{args: args}
+	ok, err := t.or()
+	if err == nil && t.pos < len(args) {
+		err = fmt.Errorf("%s: %w", args[t.pos], errUnexpectedArg)
+	}
+	return ok, err
+}
+
+type testExpr struct {
+	args []string
+	pos  int
+}
+
+func (t *testExpr) peek(arg string) bool {
+	return t.pos < len(t.args) && t.args[t.pos] == arg
+}
+
+func (t *testExpr) or() (bool, error) {
+	ok, err := t.and()
+	for err == nil && t.peek("-o") {
+		t.pos++
+		var right bool
+		right, err = t.and()
+		ok = ok || right
+	}
+	return ok, err
+}
+
+func (t *testExpr) and() (bool, error) {
+	ok, err := t.not()
+	for err == nil && t.peek("-a") {
+		t.pos++
+		var right bool
+		right, err = t.not()
+		ok = ok && right
+	}
+	return ok, err
+}
+
+func (t *testExpr) not() (bool, error) {
+	if t.peek("!") && t.pos+1 < len(t.args) {
+		t.pos++
+		ok, err := t.not()
+		return !ok, err
+	}
+	return t.primary()
+}
+
+func (t *testExpr) primary() (bool, error) {
+	if t.pos >= len(t.args) {
+		return false, fmt.Errorf("%s: %w", t.args[t.pos-1], errArgumentExpected)
+	}
+
+	rest := t.args[t.pos:]
+	switch {
+	case len(rest) >= 3 && isBinaryTest(rest[1]):
+		t.pos += 3
+		return binaryTest(rest[0], rest[1], rest[2])
+	case rest[0] == "(" && len(rest) > 1:
+		t.pos++
+		ok, err := t.or()
+		if err != nil {
+			return false, err
+		}
+		if !t.peek(")") {
+			return false, errParenExpected
+		}
+		t.pos++
+		return ok, nil
+	case len(rest) >= 2 && isUnaryTest(rest[0]):
+		t.pos += 2
+		return unaryTest(rest[0], rest[1]), nil
+	}
+
+	// A lone string is true when it is not empty.
+	t.pos++
+	return rest[0] != "", nil
+}
+
+func isUnaryTest(op string) bool {
+	switch op {
+	case "-n", "-z", "-e", "-f", "-d", "-r", "-w", "-x", "-s", "-L", "-h", "-p", "-S", "-b", "-c", "-t":
+		return true
+	}
+	return false
+}
+
+func isBinaryTest(op string) bool {
+	switch op {
+	case "=", "==", "!=", "<", ">", "-eq", "-ne", "-lt", "-le", "-gt", "-ge", "-nt", "-ot", "-ef":
+		return true
+	}
+	return false
+}
+
+func unaryTest(op, arg string) bool {
+	switch op {
+	case "-n":
+		return arg != ""
+	case "-z":
+		return arg == ""
+	case "-r":
+		return unix.Access(arg, unix.R_OK) == nil
+	case "-w":
+		return unix.Access(arg, unix.W_OK) == nil
+	case "-x":
+		return unix.Access(arg, unix.X_OK) == nil
+	case "-t":
+		fd, err := strconv.Atoi(arg)
+		return err == nil && term.IsTerminal(fd)
+	case "-L", "-h":
+		info, err := os.Lstat(arg)
+		return err == nil && info.Mode()&os.ModeSymlink != 0
+	}
+
+	info, err := os.Stat(arg)
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	switch op {
+	case "-f":
+		return mode.IsRegular()
+	case "-d":
+		return mode.IsDir()
+	case "-s":
+		return info.Size() > 0
+	case "-p":
+		return mode&os.ModeNamedPipe != 0
+	case "-S":
+		return mode&os.ModeSocket != 0
+	case "-b":
+		return mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0
+	case "-c":
+		return mode&os.ModeCharDevice != 0
+	}
+	return true // -e
+}
+
+func binaryTest(left, op, right string) (bool, error) {
+	switch op {
+	case "=", "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot", "-ef":
+		return fileTest(left, op, right), nil
+	}
+
+	a, err := strconv.ParseInt(strings.TrimSpace(left), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", left, errIntegerExpected)
+	}
+	b, err := strconv.ParseInt(strings.TrimSpace(right), 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", right, errIntegerExpected)
+	}
+
+	switch op {
+	case "-eq":
+		return a == b, nil
+	case "-ne":
+		return a != b, nil
+	case "-lt":
+		return a < b, nil
+	case "-le":
+		return a <= b, nil
+	case "-gt":
+		return a > b, nil
+	}
+	return a >= b, nil // -ge
+}
+
+// fileTest compares modification times with -nt and -ot, where a file
+// that exists is newer than one that does not, and identity with -ef.
+func fileTest(left, op, right string) bool {
+	a, errA := os.Stat(left)
+	b, errB := os.Stat(right)
+
+	switch op {
+	case "-nt":
+		return errA == nil && (errB != nil || a.ModTime().After(b.ModTime()))
+	case "-ot":
+		return errB == nil && (errA != nil || a.ModTime().Before(b.ModTime()))
+	}
+	return errA == nil && errB == nil && os.SameFile(a, b)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTest(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	empty := filepath.Join(dir, "empty")
+	link := filepath.Join(dir, "link")
+	missing := filepath.Join(dir, "missing")
+	if err := os.WriteFile(file, []byte("x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(file, link); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(empty, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{nil, 1},
+		{[]string{""}, 1},
+		{[]string{"x"}, 0},
+		{[]string{"-n"}, 0},
+		{[]string{"!"}, 0},
+		{[]string{"-n", ""}, 1},
+		{[]string{"-z", ""}, 0},
+		{[]string{"!", "-z", ""}, 1},
+		{[]string{"a", "=", "a"}, 0},
+		{[]string{"a", "==", "b"}, 1},
+		{[]string{"a", "!=", "b"}, 0},
+		{[]string{"-n", "=", "-n"}, 0},
+		{[]string{"a", "<", "b"}, 0},
+		{[]string{"10", "-gt", "9"}, 0},
+		{[]string{" 3", "-eq", "3"}, 0},
+		{[]string{"-1", "-le", "-2"}, 1},
+		{[]string{"-f", file}, 0},
+		{[]string{"-f", dir}, 1},
+		{[]string{"-d", dir}, 0},
+		{[]string{"-e", missing}, 1},
+		{[]string{"-s", file}, 0},
+		{[]string{"-s", empty}, 1},
+		{[]string{"-x", file}, 0},
+		{[]string{"-x", empty}, 1},
+		{[]string{"-L", link}, 0},
+		{[]string{"-L", file}, 1},
+		{[]string{"-c", os.DevNull}, 0},
+		{[]string{file, "-nt", empty}, 0},
+		{[]string{file, "-ot", empty}, 1},
+		{[]string{missing, "-ot", empty}, 0},
+		{[]string{link, "-ef", file}, 0},
+		{[]string{"a", "-a", ""}, 1},
+		{[]string{"a", "-o", ""}, 0},
+		{[]string{"", "-o", "a", "-a", ""}, 1},
+		{[]string{"!", "(", "a", "-o", "", ")", "-a", "b"}, 1},
+		{[]string{"(", "x", "=", "y", ")", "-o", "-d", dir}, 0},
+	}
+
+	for _, tt := range tests {
+		if status := ExitStatus(test(tt.args, nil)); status != tt.expected {
+			t.Errorf("Expected status %d for %q, got %d", tt.expected, tt.args, status)
+		}
+	}
+}
+
+func TestTestErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"a", "-lt", "3"}, "test: a: integer expression expected"},
+		{[]string{"a", "b"}, "test: b: unexpected argument"},
+		{[]string{"a", "-a"}, "test: -a: argument expected"},
+		{[]string{"(", "a"}, "test: `)' expected"},
+	}
+
+	for _, tt := range tests {
+		err := test(tt.args, nil)
+		if err == nil || err.Error() != tt.expected || ExitStatus(err) != 2 {
+			t.Errorf("Expected %q with status 2 for %q, got %v", tt.expected, tt.args, err)
+		}
+	}
+
+	if err := bracket([]string{"a", "=", "a"}, nil); err == nil || ExitStatus(err) != 2 {
+		t.Errorf("Expected a missing ] error, got %v", err)
+	}
+	if err := bracket([]string{"a", "=", "a", "]"}, nil); err != nil {
+		t.Errorf("Expected [ a = a ] to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,408 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupted is returned by ReadLine when the line is cancelled with
+// Ctrl+C.
+var ErrInterrupted = errors.New("interrupted")
+
+const (
+	keyCtrlA     = 0x01
+	keyCtrlB     = 0x02
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyCtrlF     = 0x06
+	keyCtrlG     = 0x07
+	keyCtrlH     = 0x08
+	keyCtrlK     = 0x0b
+	keyCtrlL     = 0x0c
+	keyEnter     = '\r'
+	keyNewline   = '\n'
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlR     = 0x12
+	keyCtrlU     = 0x15
+	keyCtrlW     = 0x17
+	keyEscape    = 0x1b
+	keyBackspace = 0x7f
+
+	// Keys decoded from escape sequences live above the Unicode range.
+	keyUp rune = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// Editor reads lines with Emacs-style editing, history browsing and
+// reverse-i-search. Input that is not a terminal is read line by line
+// without a prompt.
+type Editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	fd      int // terminal to switch into raw mode, or -1
+	tty     bool
+	history *History
+}
+
+// New returns an editor for in and out that assumes in is already a
+// terminal in raw mode. It is what tests and custom terminals use.
+func New(in io.Reader, out io.Writer, history *History) *Editor {
+	if history == nil {
+		history = NewHistory(1000)
+	}
+	return &Editor{in: bufio.NewReader(in), out: out, fd: -1, tty: true, history: history}
+}
+
+// NewTerminal returns an editor for the terminal in. It falls back to
+// plain line reading when in is not a terminal.
+func NewTerminal(in *os.File, out io.Writer, history *History) *Editor {
+	e := New(in, out, history)
+	e.tty = term.IsTerminal(int(in.Fd()))
+	if e.tty {
+		e.fd = int(in.Fd())
+	}
+	return e
+}
+
+func (e *Editor) History() *History {
+	return e.history
+}
+
+// ReadLine shows prompt and returns the entered line, which is also added
+// to the history. It returns io.EOF on Ctrl+D at an empty line and
+// ErrInterrupted on Ctrl+C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	line, err := e.readLine(prompt)
+	if err == nil && e.tty {
+		_ = e.history.Add(line)
+	}
+	return line, err
+}
+
+// ReadCommand reads a line like ReadLine, and then continuation lines with
+// the prompt more until complete accepts the text, joined with newlines.
+// The whole command goes to the history as one entry. Ctrl+D on a
+// continuation line ends the command as it is.
+func (e *Editor) ReadCommand(prompt, more string, complete func(text string) bool) (string, error) {
+	text, err := e.readLine(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	for !complete(text) {
+		line, err := e.readLine(more)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		text += "\n" + line
+	}
+
+	if e.tty {
+		_ = e.history.Add(text)
+	}
+	return text, nil
+}
+
+func (e *Editor) readLine(prompt string) (string, error) {
+	if !e.tty {
+		return e.readPlain()
+	}
+
+	if e.fd >= 0 {
+		state, err := term.MakeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = term.Restore(e.fd, state) }()
+	}
+
+	prompt = strings.ReplaceAll(prompt, "\n", "\r\n")
+	_, _ = io.WriteString(e.out, prompt)
+	if i := strings.LastIndex(prompt, "\n"); i >= 0 {
+		prompt = prompt[i+1:]
+	}
+
+	s := &lineState{editor: e, prompt: prompt, histIdx: e.history.Len()}
+	return s.run()
+}
+
+func (e *Editor) readPlain() (string, error) {
+	line, err := e.in.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readKey reads one key, decoding the escape sequences terminals send for
+// arrows, Home, End and Delete. A terminal sends a sequence in one go, so
+// an Esc with nothing after it yet is a key of its own; so is one that is
+// not followed by '[' or 'O', and the key after it is read next.
+func (e *Editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+	if e.in.Buffered() == 0 {
+		return keyUnknown, nil
+	}
+
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyUnknown, err
+	}
+	if next != '[' && next != 'O' {
+		_ = e.in.UnreadRune()
+		return keyUnknown, nil
+	}
+
+	var params strings.Builder
+	for {
+		c, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyUnknown, err
+		}
+		if c < 0x40 || c > 0x7e {
+			params.WriteRune(c)
+			continue
+		}
+
+		switch {
+		case c == 'A':
+			return keyUp, nil
+		case c == 'B':
+			return keyDown, nil
+		case c == 'C':
+			return keyRight, nil
+		case c == 'D':
+			return keyLeft, nil
+		case c == 'H':
+			return keyHome, nil
+		case c == 'F':
+			return keyEnd, nil
+		case c == '~':
+			switch params.String() {
+			case "1", "7":
+				return keyHome, nil
+			case "4", "8":
+				return keyEnd, nil
+			case "3":
+				return keyDelete, nil
+			}
+		}
+		return keyUnknown, nil
+	}
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	editor *Editor
+	prompt string // last line of the prompt, redrawn on every change
+	buf    []rune
+	pos    int
+
+	histIdx int    // history entry shown, History.Len() for the draft
+	draft   string // the line being typed before browsing history
+}
+
+func (s *lineState) run() (string, error) {
+	for {
+		key, err := s.editor.readKey()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(s.buf) > 0 {
+				s.write("\r\n")
+				return string(s.buf), nil
+			}
+			return "", err
+		}
+
+		if key == keyCtrlR {
+			if key, err = s.search(); err != nil {
+				return "", err
+			}
+		}
+
+		done, err := s.handle(key)
+		if done || err != nil {
+			return string(s.buf), err
+		}
+	}
+}
+
+// handle applies one key. done is true once the line is complete.
+func (s *lineState) handle(key rune) (bool, error) {
+	switch key {
+	case keyEnter, keyNewline:
+		s.write("\r\n")
+		return true, nil
+	case keyCtrlC:
+		s.write("^C\r\n")
+		s.buf = s.buf[:0]
+		return true, ErrInterrupted
+	case keyCtrlD:
+		if len(s.buf) == 0 {
+			s.write("\r\n")
+			return true, io.EOF
+		}
+		s.deleteAt(s.pos)
+	case keyDelete:
+		s.deleteAt(s.pos)
+	case keyBackspace, keyCtrlH:
+		if s.pos > 0 {
+			s.pos--
+			s.deleteAt(s.pos)
+		}
+	case keyLeft, keyCtrlB:
+		s.pos = max(s.pos-1, 0)
+	case keyRight, keyCtrlF:
+		s.pos = min(s.pos+1, len(s.buf))
+	case keyHome, keyCtrlA:
+		s.pos = 0
+	case keyEnd, keyCtrlE:
+		s.pos = len(s.buf)
+	case keyCtrlK:
+		s.buf = s.buf[:s.pos]
+	case keyCtrlU:
+		s.buf = s.buf[s.pos:]
+		s.pos = 0
+	case keyCtrlW:
+		start := s.pos
+		for start > 0 && s.buf[start-1] == ' ' {
+			start--
+		}
+		for start > 0 && s.buf[start-1] != ' ' {
+			start--
+		}
+		s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+		s.pos = start
+	case keyCtrlL:
+		s.write("\x1b[H\x1b[2J")
+	case keyUp, keyCtrlP:
+		s.browse(s.histIdx - 1)
+	case keyDown, keyCtrlN:
+		s.browse(s.histIdx + 1)
+	default:
+		if !unicode.IsPrint(key) {
+			return false, nil
+		}
+		s.buf = append(s.buf[:s.pos], append([]rune{key}, s.buf[s.pos:]...)...)
+		s.pos++
+	}
+
+	s.refresh()
+	return false, nil
+}
+
+func (s *lineState) deleteAt(i int) {
+	if i < len(s.buf) {
+		s.buf = append(s.buf[:i], s.buf[i+1:]...)
+	}
+}
+
+// browse shows history entry idx. Going past the newest entry brings back
+// the draft.
+func (s *lineState) browse(idx int) {
+	history := s.editor.history
+	if idx < 0 || idx > history.Len() {
+		return
+	}
+
+	if s.histIdx == history.Len() {
+		s.draft = string(s.buf)
+	}
+	s.histIdx = idx
+
+	line := s.draft
+	if idx < history.Len() {
+		line = history.At(idx)
+	}
+	s.buf = []rune(line)
+	s.pos = len(s.buf)
+}
+
+// search runs reverse-i-search. Typing extends the query, Ctrl+R looks for
+// an older match, Ctrl+G and Ctrl+C give up. Any other key takes the match
+// into the line and is returned to be handled as usual.
+func (s *lineState) search() (rune, error) {
+	history := s.editor.history
+	var query []rune
+	idx, found := history.Len(), true
+	match := string(s.buf)
+
+	for {
+		status := "reverse-i-search"
+		if !found {
+			status = "failing reverse-i-search"
+		}
+		s.write(fmt.Sprintf("\r(%s)`%s': %s\x1b[K", status, string(query), displayText(match)))
+
+		key, err := s.editor.readKey()
+		if err != nil {
+			return 0, err
+		}
+
+		from := idx + 1 // the current match still counts
+		switch {
+		case key == keyCtrlR:
+			from = idx
+		case key == keyBackspace || key == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+		case key == keyCtrlG || key == keyCtrlC:
+			s.refresh()
+			if key == keyCtrlC {
+				return keyCtrlC, nil
+			}
+			return keyUnknown, nil
+		case unicode.IsPrint(key):
+			query = append(query, key)
+		default:
+			s.buf = []rune(match)
+			s.pos = len(s.buf)
+			s.histIdx = history.Len()
+			return key, nil
+		}
+
+		if i, ok := history.SearchBackward(string(query), from); ok {
+			idx, match, found = i, history.At(i), true
+		} else {
+			found = false
+		}
+	}
+}
+
+func (s *lineState) refresh() {
+	s.write(fmt.Sprintf("\r%s%s\x1b[K", s.prompt, displayText(string(s.buf))))
+	if back := len(s.buf) - s.pos; back > 0 {
+		s.write(fmt.Sprintf("\x1b[%dD", back))
+	}
+}
+
+// displayText keeps a command from the history that spans lines on one
+// line of the screen, showing each newline as ↵.
+func displayText(text string) string {
+	return strings.ReplaceAll(text, "\n", "↵")
+}
+
+func (s *lineState) write(text string) {
+	_, _ = io.WriteString(s.editor.out, text)
+}
@@ -0,0 +1,215 @@
+package lineedit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readLine(t *testing.T, history *History, keys string) (string, error) {
+	t.Helper()
+	return New(strings.NewReader(keys), &bytes.Buffer{}, history).ReadLine("$ ")
+}
+
+func TestEditing(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{"plain", "echo hi\r", "echo hi"},
+		{"backspace", "echo hix\x7f\r", "echo hi"},
+		{"arrows", "eho\x1b[D\x1b[Dc\x1b[C\x1b[C!\r", "echo!"},
+		{"home and end", "cho\x1b[Hе\x1b[F.\r", "еcho."},
+		{"ctrl-a and ctrl-e", "b\x01a\x05c\r", "abc"},
+		{"delete", "abc\x01\x1b[3~\r", "bc"},
+		{"kill to end", "abcdef\x01\x06\x06\x0b\r", "ab"},
+		{"kill to start", "abcdef\x02\x02\x15\r", "ef"},
+		{"delete word", "echo hello world\x17\r", "echo hello "},
+		{"unicode", "привет\x7f\r", "приве"},
+		{"eof with text", "partial", "partial"},
+		{"lone escape", "ab\x1bc\r", "abc"},
+		{"escape at the end", "ab\x1b", "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, err := readLine(t, nil, tt.keys)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestControlKeys(t *testing.T) {
+	if _, err := readLine(t, nil, "\x04"); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF on Ctrl+D, got %v", err)
+	}
+	if _, err := readLine(t, nil, "echo\x03"); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Expected ErrInterrupted on Ctrl+C, got %v", err)
+	}
+
+	line, err := readLine(t, nil, "ab\x01\x04\r")
+	if err != nil || line != "b" {
+		t.Errorf("Expected Ctrl+D to delete under the cursor, got %q, %v", line, err)
+	}
+}
+
+func TestHistoryBrowsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{"previous", "\x1b[A\r", "second"},
+		{"two back", "\x1b[A\x1b[A\r", "first"},
+		{"stops at oldest", "\x1b[A\x1b[A\x1b[A\r", "first"},
+		{"draft comes back", "dra\x1b[A\x1b[Bft\r", "draft"},
+		{"edit entry", "\x10\x10!\r", "first!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := NewHistory(10)
+			_ = history.Add("first")
+			_ = history.Add("second")
+
+			line, err := readLine(t, history, tt.keys)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestReverseSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{"newest match", "\x12make\r", "make test"},
+		{"older match", "\x12make\x12\r", "make build"},
+		{"query narrows", "\x12test\r", "make test"},
+		{"backspace after a miss", "\x12testx\x7f\r", "make test"},
+		{"edit after search", "\x12go\x05 -v\r", "go test ./... -v"},
+		{"cancel", "typed\x12make\x07\r", "typed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := NewHistory(10)
+			for _, line := range []string{"make build", "go test ./...", "make test", "ls"} {
+				_ = history.Add(line)
+			}
+
+			line, err := readLine(t, history, tt.keys)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if line != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, line)
+			}
+		})
+	}
+}
+
+func TestPersistentHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	history, err := OpenHistory(path, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, line := range []string{"one", "two", "two", " ", "three", "four"} {
+		if err = history.Add(line); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "one\ntwo\nthree\nfour\n" {
+		t.Errorf("Unexpected history file %q", data)
+	}
+
+	reopened, err := OpenHistory(path, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reopened.Len() != 3 || reopened.At(0) != "two" {
+		t.Errorf("Expected the last 3 entries, got %d starting with %q", reopened.Len(), reopened.At(0))
+	}
+
+	data, _ = os.ReadFile(path)
+	if string(data) != "two\nthree\nfour\n" {
+		t.Errorf("Expected the file to be trimmed, got %q", data)
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	history := NewHistory(10)
+	editor := New(strings.NewReader("echo 'a\rb'\rls\r"), &bytes.Buffer{}, history)
+	complete := func(text string) bool { return strings.Count(text, "'")%2 == 0 }
+
+	for _, expected := range []string{"echo 'a\nb'", "ls"} {
+		text, err := editor.ReadCommand("$ ", "> ", complete)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if text != expected {
+			t.Errorf("Expected %q, got %q", expected, text)
+		}
+	}
+
+	if history.Len() != 2 || history.At(0) != "echo 'a\nb'" {
+		t.Errorf("Expected the command as one history entry, got %d entries", history.Len())
+	}
+
+	line, err := New(strings.NewReader("\x1b[A\x1b[A\r"), &bytes.Buffer{}, history).ReadLine("$ ")
+	if err != nil || line != "echo 'a\nb'" {
+		t.Errorf("Expected to recall the whole command, got %q, %v", line, err)
+	}
+}
+
+func TestPersistentMultilineHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	history, err := OpenHistory(path, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, line := range []string{"old", "cat <<EOF\nbody\nEOF", "ls"} {
+		if err = history.Add(line); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "old\ncat <<EOF\\\nbody\\\nEOF\nls\n" {
+		t.Errorf("Unexpected history file %q", data)
+	}
+
+	reopened, err := OpenHistory(path, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reopened.Len() != 2 || reopened.At(0) != "cat <<EOF\nbody\nEOF" || reopened.At(1) != "ls" {
+		t.Errorf("Expected the multi-line entry back whole, got %d entries starting with %q", reopened.Len(), reopened.At(0))
+	}
+
+	data, _ = os.ReadFile(path)
+	if string(data) != "cat <<EOF\\\nbody\\\nEOF\nls\n" {
+		t.Errorf("Expected the file to be trimmed, got %q", data)
+	}
+}
@@ -0,0 +1,127 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+)
+
+// History is the list of entered lines, oldest first. When it has a file,
+// every added line is appended to it right away, so history survives a
+// crashed or killed shell. An entry that spans lines is stored with a
+// backslash at the end of all its lines but the last, as zsh does; a
+// single-line entry that ends with a backslash reads back joined to the
+// next one.
+type History struct {
+	entries []string
+	path    string
+	limit   int
+}
+
+// NewHistory returns an in-memory history keeping at most limit lines.
+func NewHistory(limit int) *History {
+	return &History{limit: limit}
+}
+
+// OpenHistory loads the history file at path. A missing file is not an
+// error. On other errors the returned history still works, in memory only.
+func OpenHistory(path string, limit int) (*History, error) {
+	h := NewHistory(limit)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		h.path = path
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	var entry strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if more, ok := strings.CutSuffix(line, historyContinuation); ok {
+			entry.WriteString(more + "\n")
+			continue
+		}
+		entry.WriteString(line)
+		if entry.Len() > 0 {
+			h.entries = append(h.entries, entry.String())
+		}
+		entry.Reset()
+	}
+	if err = scanner.Err(); err != nil {
+		return h, err
+	}
+
+	h.path = path
+	if len(h.entries) > limit {
+		h.entries = h.entries[len(h.entries)-limit:]
+		return h, h.rewrite()
+	}
+	return h, nil
+}
+
+// Add appends line unless it is blank or repeats the previous entry.
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" || (h.Len() > 0 && h.entries[h.Len()-1] == line) {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	if h.Len() > h.limit {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteString(encodeEntry(line)); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At returns the i-th entry, 0 being the oldest.
+func (h *History) At(i int) string {
+	return h.entries[i]
+}
+
+// SearchBackward finds the newest entry before index from that contains
+// query.
+func (h *History) SearchBackward(query string, from int) (int, bool) {
+	for i := min(from, h.Len()) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func (h *History) rewrite() error {
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(encodeEntry(entry))
+	}
+	return os.WriteFile(h.path, []byte(b.String()), 0o600)
+}
+
+const historyContinuation = "\\"
+
+// encodeEntry formats an entry as lines of the history file.
+func encodeEntry(entry string) string {
+	return strings.ReplaceAll(entry, "\n", historyContinuation+"\n") + "\n"
+}
@@ -0,0 +1,142 @@
+package parser
+
+// List is a sequence of and-or lists separated by ";" or newlines. A
+// whole script is a List.
+type List struct {
+	Items []*AndOr
+}
+
+// AndOr is a chain of pipelines joined by "&&" and "||". Ops[i] joins
+// Pipelines[i] and Pipelines[i+1]. Background is set when it ends with
+// "&".
+type AndOr struct {
+	Pipelines  []*Pipeline
+	Ops        []string
+	Background bool
+}
+
+// Pipeline is "[!] cmd1 | cmd2 | ...".
+type Pipeline struct {
+	Negate   bool
+	Commands []Command
+}
+
+// Command is a node that can be a stage of a pipeline: a simple command,
+// a compound command or a function definition.
+type Command interface {
+	command()
+	String() string
+}
+
+// SimpleCommand is "[NAME=value]... [word]... [redirection]...". Words may
+// be empty when the command only assigns or redirects.
+type SimpleCommand struct {
+	Assigns   []*Assign
+	Words     []*Word
+	Redirects []*Redirect
+}
+
+func (*SimpleCommand) command() {}
+
+// IfClause is "if list; then list; [elif list; then list;]... [else
+// list;] fi". Conds[i] guards Thens[i].
+type IfClause struct {
+	Conds     []*List
+	Thens     []*List
+	Else      *List
+	Redirects []*Redirect
+}
+
+// WhileClause is "while list; do list; done", or with Until set "until
+// list; do list; done".
+type WhileClause struct {
+	Until     bool
+	Cond      *List
+	Body      *List
+	Redirects []*Redirect
+}
+
+// ForClause is "for name in word...; do list; done". Without "in" the
+// loop goes over the positional parameters.
+type ForClause struct {
+	Name       string
+	Items      []*Word
+	Positional bool
+	Body       *List
+	Redirects  []*Redirect
+}
+
+// CaseClause is "case word in pattern|pattern) list;; ... esac".
+type CaseClause struct {
+	Word      *Word
+	Items     []*CaseItem
+	Redirects []*Redirect
+}
+
+type CaseItem struct {
+	Patterns []*Word
+	Body     *List
+}
+
+// BraceGroup is "{ list; }".
+type BraceGroup struct {
+	List      *List
+	Redirects []*Redirect
+}
+
+// FuncDef is "name() compound-command".
+type FuncDef struct {
+	Name string
+	Body Command
+}
+
+func (*IfClause) command()    {}
+func (*WhileClause) command() {}
+func (*ForClause) command()   {}
+func (*CaseClause) command()  {}
+func (*BraceGroup) command()  {}
+func (*FuncDef) command()     {}
+
+type Assign struct {
+	Name  string
+	Value *Word
+}
+
+// Redirect is a redirection with its descriptor resolved: "<x" is Fd 0,
+// ">x" is Fd 1. Op is one of < > >> <& >& &> &>> << <<-.
+type Redirect struct {
+	Fd     int
+	Op     string
+	Target *Word // file, descriptor or here-document delimiter
+	Body   *Word // here-document body
+}
+
+// Word is one shell word before expansion.
+type Word struct {
+	Raw   string // source text, used for reserved words and delimiters
+	Parts []WordPart
+}
+
+// WordPart is a Lit or a Param.
+type WordPart interface {
+	wordPart()
+}
+
+// Lit is literal text. Quoted text is never split or, later, globbed.
+type Lit struct {
+	Value  string
+	Quoted bool
+}
+
+// Param is a parameter expansion: $name, ${name}, ${#name} or
+// ${name<op>word} with op one of - :- = := ? :? + :+.
+type Param struct {
+	Name   string
+	Length bool
+	Op     string
+	Arg    *Word
+	Quoted bool
+}
+
+func (*Lit) wordPart()   {}
+func (*Param) wordPart() {}
@@ -0,0 +1,314 @@
+package parser
+
+// closingWords end the lists inside compound commands.
+var closingWords = []string{"then", "elif", "else", "fi", "do", "done", "esac", "}"}
+
+func (p *parser) atClosingWord() bool {
+	for _, w := range closingWords {
+		if p.isReserved(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// expect consumes the reserved word or operator w, which has to come
+// next. At the end of the input the command is incomplete.
+func (p *parser) expect(w string) error {
+	switch {
+	case p.tok.kind == tokEOF:
+		return ErrIncomplete
+	case p.tok.kind == tokNewline || p.tok.text != w:
+		return unexpected(p.tok.line, p.tok.text)
+	}
+	return p.advance()
+}
+
+// parseBody parses the list inside a compound command, which must not be
+// empty.
+func (p *parser) parseBody() (*List, error) {
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		if p.tok.kind == tokEOF {
+			return nil, ErrIncomplete
+		}
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+	return list, nil
+}
+
+func (p *parser) parseIf() (*IfClause, error) {
+	clause := &IfClause{}
+
+	for {
+		// The if or elif.
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("then"); err != nil {
+			return nil, err
+		}
+		then, err := p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+		clause.Conds = append(clause.Conds, cond)
+		clause.Thens = append(clause.Thens, then)
+
+		if !p.isReserved("elif") {
+			break
+		}
+	}
+
+	if p.isReserved("else") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		body, err := p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+		clause.Else = body
+	}
+
+	if err := p.expect("fi"); err != nil {
+		return nil, err
+	}
+	redirects, err := p.parseRedirects()
+	clause.Redirects = redirects
+	return clause, err
+}
+
+func (p *parser) parseWhile() (*WhileClause, error) {
+	clause := &WhileClause{Until: p.isReserved("until")}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	clause.Cond = cond
+
+	if clause.Body, err = p.parseDoGroup(); err != nil {
+		return nil, err
+	}
+	clause.Redirects, err = p.parseRedirects()
+	return clause, err
+}
+
+// parseDoGroup parses "do list; done".
+func (p *parser) parseDoGroup() (*List, error) {
+	if err := p.expect("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	return body, p.expect("done")
+}
+
+func (p *parser) parseFor() (*ForClause, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, ErrIncomplete
+	}
+	if p.tok.kind != tokWord || !IsName(p.tok.text) {
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+	clause := &ForClause{Name: p.tok.text, Positional: true}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+	if p.isReserved("in") {
+		clause.Positional = false
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for p.tok.kind == tokWord {
+			clause.Items = append(clause.Items, p.tok.word)
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The words end with ; or a newline; without "in" the ; is optional.
+	if p.isOp(";") || p.tok.kind == tokNewline {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	} else if !clause.Positional && p.tok.kind != tokEOF {
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseDoGroup()
+	if err != nil {
+		return nil, err
+	}
+	clause.Body = body
+	clause.Redirects, err = p.parseRedirects()
+	return clause, err
+}
+
+func (p *parser) parseCase() (*CaseClause, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, ErrIncomplete
+	}
+	if p.tok.kind != tokWord {
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+	clause := &CaseClause{Word: p.tok.word}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("in"); err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+		if p.isReserved("esac") {
+			break
+		}
+
+		item, err := p.parseCaseItem()
+		if err != nil {
+			return nil, err
+		}
+		clause.Items = append(clause.Items, item)
+
+		// The last item may end without ;;.
+		if !p.isOp(";;") {
+			if err = p.skipNewlines(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("esac"); err != nil {
+		return nil, err
+	}
+	redirects, err := p.parseRedirects()
+	clause.Redirects = redirects
+	return clause, err
+}
+
+// parseCaseItem parses "[(] pattern [| pattern]... ) [list]".
+func (p *parser) parseCaseItem() (*CaseItem, error) {
+	item := &CaseItem{}
+	if p.isOp("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if p.tok.kind == tokEOF {
+			return nil, ErrIncomplete
+		}
+		if p.tok.kind != tokWord {
+			return nil, unexpected(p.tok.line, p.tok.text)
+		}
+		item.Patterns = append(item.Patterns, p.tok.word)
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		if !p.isOp("|") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	item.Body = body
+	return item, nil
+}
+
+func (p *parser) parseBraceGroup() (*BraceGroup, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	list, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	group := &BraceGroup{List: list}
+	group.Redirects, err = p.parseRedirects()
+	return group, err
+}
+
+// parseFuncDef parses the rest of "name() compound-command" after the
+// name. The body may start on the next line.
+func (p *parser) parseFuncDef(name *Word) (*FuncDef, error) {
+	if !IsName(name.Raw) {
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.tok.kind == tokEOF:
+		return nil, ErrIncomplete
+	case p.isReserved("if"), p.isReserved("while"), p.isReserved("until"),
+		p.isReserved("for"), p.isReserved("case"), p.isReserved("{"):
+	default:
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+
+	body, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+	return &FuncDef{Name: name.Raw, Body: body}, nil
+}
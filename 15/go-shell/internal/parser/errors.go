@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIncomplete is returned when the input ends inside a quote, a
+// here-document or after an operator that needs more. An interactive
+// shell reads another line and parses again.
+var ErrIncomplete = errors.New("unexpected end of input")
+
+var (
+	errHereDocEOF      = fmt.Errorf("%w in here-document", ErrIncomplete)
+	errBadSubstitution = errors.New("bad substitution")
+	errUnsupported     = errors.New("command substitution is not supported")
+)
+
+// SyntaxError reports malformed input.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func unexpected(line int, token string) *SyntaxError {
+	return &SyntaxError{Line: line, Msg: fmt.Sprintf("syntax error near unexpected token `%s'", token)}
+}
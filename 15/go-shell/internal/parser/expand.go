@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errParameterNotSet = errors.New("parameter null or not set")
+	errUnboundVariable = errors.New("unbound variable")
+)
+
+// Env gives expansions access to the shell variables and the special
+// parameters such as $? and $$. Positional returns $1, $2 and so on, which
+// "$@" expands to as separate fields.
+type Env interface {
+	Get(name string) (string, bool)
+	Set(name, value string) error
+	Positional() []string
+}
+
+// Fields expands w into the fields of a command line. The results of
+// unquoted expansions are split on blanks; quoted text never is, so ""
+// still yields one empty field. A field with an unquoted *, ? or [ is
+// replaced by the paths it matches, and kept as it is when none does.
+func Fields(w *Word, env Env) ([]string, error) {
+	var fields []string
+	var f field
+	started := false
+
+	end := func() {
+		fields = append(fields, f.expand()...)
+		f = field{}
+	}
+
+	for i, part := range w.Parts {
+		switch part := part.(type) {
+		case *Lit:
+			value := part.Value
+			if i == 0 && !part.Quoted {
+				// The home directory is not a pattern, what follows it is.
+				if expanded := expandTilde(value, env); expanded != value {
+					f.write(strings.TrimSuffix(expanded, value[1:]), true)
+					value = value[1:]
+					started = true
+				}
+			}
+			f.write(value, part.Quoted)
+			started = started || part.Quoted || value != ""
+		case *Param:
+			if part.Quoted && part.Name == "@" && part.Op == "" && !part.Length {
+				// "$@" ends the field at every parameter but the last.
+				for j, arg := range env.Positional() {
+					if j > 0 {
+						end()
+					}
+					f.write(arg, true)
+					started = true
+				}
+				continue
+			}
+
+			value, err := expandParam(part, env)
+			if err != nil {
+				return nil, err
+			}
+			if part.Quoted {
+				f.write(value, true)
+				started = true
+				continue
+			}
+
+			for j := 0; j < len(value); j++ {
+				if !isBlank(value[j]) {
+					f.write(value[j:j+1], false)
+					started = true
+					continue
+				}
+				if started {
+					end()
+					started = false
+				}
+			}
+		}
+	}
+
+	if started {
+		end()
+	}
+	return fields, nil
+}
+
+// field is a field being expanded: its text, and the same text as a
+// pattern for Match with the quoted characters escaped.
+type field struct {
+	text, pattern strings.Builder
+	glob          bool // has an unquoted *, ? or [
+}
+
+func (f *field) write(s string, quoted bool) {
+	f.text.WriteString(s)
+	if quoted {
+		f.pattern.WriteString(escapePattern(s))
+		return
+	}
+	f.pattern.WriteString(s)
+	f.glob = f.glob || strings.ContainsAny(s, "*?[")
+}
+
+func (f *field) expand() []string {
+	if f.glob {
+		if paths := glob(f.pattern.String()); len(paths) > 0 {
+			return paths
+		}
+	}
+	return []string{f.text.String()}
+}
+
+// Expand expands w into a single string without field splitting, as done
+// for assignments, redirection targets and here-document bodies.
+func Expand(w *Word, env Env) (string, error) {
+	if w == nil {
+		return "", nil
+	}
+
+	var b strings.Builder
+	for i, part := range w.Parts {
+		switch part := part.(type) {
+		case *Lit:
+			if i == 0 && !part.Quoted {
+				b.WriteString(expandTilde(part.Value, env))
+				continue
+			}
+			b.WriteString(part.Value)
+		case *Param:
+			value, err := expandParam(part, env)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+		}
+	}
+	return b.String(), nil
+}
+
+// expandParam expands one parameter. With set -u, that is with u in $-,
+// a plain expansion of an unset parameter is an error.
+func expandParam(p *Param, env Env) (string, error) {
+	value, set := env.Get(p.Name)
+	if !set && (p.Op == "" || p.Length) && p.Name != "@" && p.Name != "*" {
+		if flags, _ := env.Get("-"); strings.Contains(flags, "u") {
+			return "", fmt.Errorf("%s: %w", p.Name, errUnboundVariable)
+		}
+	}
+	if p.Length {
+		return strconv.Itoa(utf8.RuneCountInString(value)), nil
+	}
+
+	// With a colon, an empty value counts as unset.
+	null := !set || (strings.HasPrefix(p.Op, ":") && value == "")
+
+	switch strings.TrimPrefix(p.Op, ":") {
+	case "-":
+		if null {
+			return Expand(p.Arg, env)
+		}
+	case "=":
+		if null {
+			if !IsName(p.Name) {
+				return "", fmt.Errorf("$%s: cannot assign in this way", p.Name)
+			}
+			arg, err := Expand(p.Arg, env)
+			if err != nil {
+				return "", err
+			}
+			return arg, env.Set(p.Name, arg)
+		}
+	case "?":
+		if null {
+			msg, err := Expand(p.Arg, env)
+			if err != nil {
+				return "", err
+			}
+			if msg == "" {
+				return "", fmt.Errorf("%s: %w", p.Name, errParameterNotSet)
+			}
+			return "", fmt.Errorf("%s: %s", p.Name, msg)
+		}
+	case "+":
+		if null {
+			return "", nil
+		}
+		return Expand(p.Arg, env)
+	}
+
+	return value, nil
+}
+
+// expandTilde replaces a leading ~ with $HOME in ~ and ~/path.
+func expandTilde(s string, env Env) string {
+	if s != "~" && !strings.HasPrefix(s, "~/") {
+		return s
+	}
+	home, ok := env.Get("HOME")
+	if !ok {
+		return s
+	}
+	return home + s[1:]
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
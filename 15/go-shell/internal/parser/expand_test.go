@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParameterExpansion(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{`echo ${V} ${V}x $Vx`, []string{"echo", "set", "setx"}},
+		{`echo ${#V} ${#UNSET} ${#U}`, []string{"echo", "3", "0", "2"}},
+		{`echo ${UNSET-def} ${EMPTY-def} ${EMPTY:-def} ${V:-def}`, []string{"echo", "def", "def", "set"}},
+		{`echo ${UNSET+alt} ${EMPTY+alt} ${EMPTY:+alt} ${V:+alt}`, []string{"echo", "alt", "alt"}},
+		{`echo "${UNSET:-a  b}" ${UNSET:-a  b}`, []string{"echo", "a  b", "a", "b"}},
+		{`echo ${UNSET:-$V}`, []string{"echo", "set"}},
+		{`echo $? $$ $1`, []string{"echo", "0", "42"}},
+		{`echo ${NEW:=assigned} $NEW`, []string{"echo", "assigned", "assigned"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			env := mapEnv{"V": "set", "EMPTY": "", "U": "üß", "?": "0", "$": "42"}
+			if got := words(t, tt.src, env); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPositionalParameters(t *testing.T) {
+	tests := []struct {
+		src      string
+		env      mapEnv
+		expected []string
+	}{
+		{`echo "$@"`, mapEnv{"1": "a b", "2": "c"}, []string{"echo", "a b", "c"}},
+		{`echo x"$@"y`, mapEnv{"1": "a b", "2": "c"}, []string{"echo", "xa b", "cy"}},
+		{`echo "$@" $1`, mapEnv{}, []string{"echo"}},
+		{`echo "$@" "$1"`, mapEnv{"1": ""}, []string{"echo", "", ""}},
+		{`echo $@ "$*"`, mapEnv{"@": "a b", "*": "a b"}, []string{"echo", "a", "b", "a b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := words(t, tt.src, tt.env); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPathnameExpansion(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt", ".hidden.go", "sub/d.go", "sub/e.md"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		src      string
+		env      mapEnv
+		expected []string
+	}{
+		{`echo *.go`, mapEnv{}, []string{"echo", "a.go", "b.go"}},
+		{`echo .*.go ?.txt`, mapEnv{}, []string{"echo", ".hidden.go", "c.txt"}},
+		{`echo [ab].go [!ab].*`, mapEnv{}, []string{"echo", "a.go", "b.go", "c.txt"}},
+		{`echo */*.go sub/*`, mapEnv{}, []string{"echo", "sub/d.go", "sub/d.go", "sub/e.md"}},
+		{`echo */`, mapEnv{}, []string{"echo", "sub/"}},
+		{`echo $D/sub/*.md`, mapEnv{"D": dir}, []string{"echo", dir + "/sub/e.md"}},
+		{`echo $P`, mapEnv{"P": "*.txt *.md"}, []string{"echo", "c.txt", "*.md"}},
+		{`echo *.none "*.go" \*.go "$P"`, mapEnv{"P": "*.go"}, []string{"echo", "*.none", "*.go", "*.go", "*.go"}},
+		{`echo a"*".go`, mapEnv{}, []string{"echo", "a*.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := words(t, tt.src, tt.env); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNoUnset(t *testing.T) {
+	env := mapEnv{"-": "u", "1": "x"}
+	if got := words(t, `echo ${UNSET-d} ${UNSET:+a} $1 "$@"`, env); !reflect.DeepEqual(got, []string{"echo", "d", "x", "x"}) {
+		t.Errorf("Expected defaults and set parameters to expand, got %q", got)
+	}
+
+	for _, src := range []string{`echo $UNSET`, `echo ${#UNSET}`, `echo "$2"`} {
+		list, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w := list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand).Words[1]
+		if _, err = Fields(w, env); err == nil || !strings.Contains(err.Error(), "unbound variable") {
+			t.Errorf("Expected an unbound variable error for %s, got %v", src, err)
+		}
+	}
+}
+
+func TestParameterExpansionErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`${UNSET?}`, "UNSET: parameter null or not set"},
+		{`${EMPTY:?must be set}`, "EMPTY: must be set"},
+		{`${1:=x}`, "cannot assign"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			list, err := Parse("echo " + tt.src)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			w := list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand).Words[1]
+			_, err = Fields(w, mapEnv{"EMPTY": ""})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error with %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"strings"
+)
+
+// glob returns the paths that pattern matches, sorted. Each element of the
+// path is matched on its own, so * does not match '/', and a name starting
+// with '.' is only matched by a pattern that starts with '.' too.
+func glob(pattern string) []string {
+	paths := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		paths = []string{"/"}
+		pattern = strings.TrimLeft(pattern, "/")
+	}
+
+	for _, elem := range strings.Split(pattern, "/") {
+		var next []string
+		for _, dir := range paths {
+			if !hasMeta(elem) {
+				next = append(next, joinPath(dir, unescapePattern(elem)))
+				continue
+			}
+
+			entries, err := os.ReadDir(cmp.Or(dir, "."))
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				name := e.Name()
+				if strings.HasPrefix(name, ".") && !strings.HasPrefix(elem, ".") {
+					continue
+				}
+				if Match(elem, name) {
+					next = append(next, joinPath(dir, name))
+				}
+			}
+		}
+		paths = next
+	}
+
+	// The elements without a pattern were not looked up.
+	paths = slices.DeleteFunc(paths, func(path string) bool {
+		_, err := os.Lstat(path)
+		return err != nil
+	})
+	slices.Sort(paths)
+	return paths
+}
+
+// joinPath appends name to dir, which is empty for the working directory.
+func joinPath(dir, name string) string {
+	if dir == "" || strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+// hasMeta reports whether pattern has a *, ? or [ that is not escaped.
+func hasMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+func unescapePattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,450 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokWord
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string // operator, or the raw text of a word
+	word *Word
+	fd   int // descriptor written before a redirection, as in 2>, or -1
+	line int
+}
+
+// operators are matched longest first.
+var operators = []string{
+	"&>>", "<<-",
+	"&&", "||", ";;", ">>", "<<", ">&", "<&", "&>",
+	";", "&", "|", "<", ">", "(", ")",
+}
+
+type lexer struct {
+	src  string
+	pos  int
+	line int
+
+	// hereDocs wait for their bodies, which start after the next newline.
+	hereDocs []*Redirect
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipBlanks()
+
+	if l.pos >= len(l.src) {
+		if len(l.hereDocs) > 0 {
+			return token{}, errHereDocEOF
+		}
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+
+	if l.src[l.pos] == '\n' {
+		tok := token{kind: tokNewline, text: "newline", line: l.line}
+		l.pos++
+		l.line++
+		return tok, l.readHereDocs()
+	}
+
+	// Digits right before < or > name the descriptor to redirect.
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos > start && l.pos < len(l.src) && (l.src[l.pos] == '<' || l.src[l.pos] == '>') {
+		fd, err := strconv.Atoi(l.src[start:l.pos])
+		if err != nil {
+			return token{}, &SyntaxError{Line: l.line, Msg: "bad file descriptor " + l.src[start:l.pos]}
+		}
+		return token{kind: tokOp, text: l.operator(), fd: fd, line: l.line}, nil
+	}
+	l.pos = start
+
+	if op := l.operator(); op != "" {
+		return token{kind: tokOp, text: op, fd: -1, line: l.line}, nil
+	}
+
+	line := l.line
+	parts, err := l.readParts(isWordEnd)
+	if err != nil {
+		return token{}, err
+	}
+	raw := l.src[start:l.pos]
+	return token{kind: tokWord, text: raw, word: &Word{Raw: raw, Parts: parts}, fd: -1, line: line}, nil
+}
+
+// skipBlanks skips blanks, escaped newlines and comments.
+func (l *lexer) skipBlanks() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t':
+			l.pos++
+		case c == '\\' && strings.HasPrefix(l.src[l.pos+1:], "\n"):
+			l.pos += 2
+			l.line++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) operator() string {
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func isWordEnd(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || strings.IndexByte("|&;<>()", c) >= 0
+}
+
+// readParts reads an unquoted word up to a byte for which end is true.
+// Quotes, escapes and parameter expansions inside are decoded.
+func (l *lexer) readParts(end func(byte) bool) ([]WordPart, error) {
+	var parts []WordPart
+	var lit strings.Builder
+
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, &Lit{Value: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for l.pos < len(l.src) && !end(l.src[l.pos]) {
+		switch c := l.src[l.pos]; c {
+		case '\\':
+			if l.pos+1 == len(l.src) {
+				return nil, ErrIncomplete
+			}
+			next := l.src[l.pos+1]
+			l.pos += 2
+			if next == '\n' {
+				l.line++
+				continue
+			}
+			flush()
+			parts = append(parts, &Lit{Value: l.src[l.pos-1 : l.pos], Quoted: true})
+		case '\'':
+			closing := strings.IndexByte(l.src[l.pos+1:], '\'')
+			if closing < 0 {
+				return nil, ErrIncomplete
+			}
+			text := l.src[l.pos+1 : l.pos+1+closing]
+			l.pos += closing + 2
+			l.line += strings.Count(text, "\n")
+			flush()
+			parts = append(parts, &Lit{Value: text, Quoted: true})
+		case '"':
+			l.pos++
+			quoted, err := l.readDoubleQuoted(true)
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			parts = append(parts, quoted...)
+		case '$':
+			param, err := l.readDollar(false)
+			if err != nil {
+				return nil, err
+			}
+			if param == nil {
+				lit.WriteByte('$')
+				continue
+			}
+			flush()
+			parts = append(parts, param)
+		case '`':
+			return nil, &SyntaxError{Line: l.line, Msg: errUnsupported.Error()}
+		default:
+			if c == '\n' {
+				l.line++
+			}
+			lit.WriteByte(c)
+			l.pos++
+		}
+	}
+
+	flush()
+	return parts, nil
+}
+
+// readDoubleQuoted reads the inside of "..." after the opening quote. With
+// terminated false it reads a here-document body to the end of the input
+// instead, where quotes are ordinary characters.
+func (l *lexer) readDoubleQuoted(terminated bool) ([]WordPart, error) {
+	parts := []WordPart{}
+	var lit strings.Builder
+
+	flush := func() {
+		if lit.Len() > 0 {
+			parts = append(parts, &Lit{Value: lit.String(), Quoted: true})
+			lit.Reset()
+		}
+	}
+
+	for {
+		if l.pos >= len(l.src) {
+			if terminated {
+				return nil, ErrIncomplete
+			}
+			flush()
+			return parts, nil
+		}
+
+		switch c := l.src[l.pos]; {
+		case c == '"' && terminated:
+			l.pos++
+			flush()
+			if len(parts) == 0 {
+				// "" is still an (empty) word.
+				parts = append(parts, &Lit{Quoted: true})
+			}
+			return parts, nil
+		case c == '\\':
+			if l.pos+1 == len(l.src) {
+				if terminated {
+					return nil, ErrIncomplete
+				}
+				lit.WriteByte(c)
+				l.pos++
+				continue
+			}
+			switch next := l.src[l.pos+1]; {
+			case next == '\n':
+				l.line++
+			case next == '$' || next == '`' || next == '\\' || (next == '"' && terminated):
+				lit.WriteByte(next)
+			default:
+				lit.WriteByte(c)
+				l.pos++
+				continue
+			}
+			l.pos += 2
+		case c == '$':
+			param, err := l.readDollar(true)
+			if err != nil {
+				return nil, err
+			}
+			if param == nil {
+				lit.WriteByte('$')
+				continue
+			}
+			flush()
+			parts = append(parts, param)
+		case c == '`':
+			return nil, &SyntaxError{Line: l.line, Msg: errUnsupported.Error()}
+		default:
+			if c == '\n' {
+				l.line++
+			}
+			lit.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+// readDollar reads a parameter expansion starting at '$'. It returns nil
+// when the '$' is just a dollar sign.
+func (l *lexer) readDollar(quoted bool) (*Param, error) {
+	l.pos++
+	if l.pos >= len(l.src) {
+		return nil, nil
+	}
+
+	switch c := l.src[l.pos]; {
+	case c == '{':
+		l.pos++
+		return l.readBraced(quoted)
+	case c == '(':
+		return nil, &SyntaxError{Line: l.line, Msg: errUnsupported.Error()}
+	case isSpecialParam(c) || isDigit(c):
+		l.pos++
+		return &Param{Name: string(c), Quoted: quoted}, nil
+	case isNameStart(c):
+		start := l.pos
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return &Param{Name: l.src[start:l.pos], Quoted: quoted}, nil
+	}
+
+	return nil, nil
+}
+
+// readBraced reads ${name}, ${#name} and ${name<op>word} after the "${".
+func (l *lexer) readBraced(quoted bool) (*Param, error) {
+	bad := &SyntaxError{Line: l.line, Msg: errBadSubstitution.Error()}
+	p := &Param{Quoted: quoted}
+
+	if strings.HasPrefix(l.src[l.pos:], "#") && l.pos+1 < len(l.src) && l.src[l.pos+1] != '}' {
+		p.Length = true
+		l.pos++
+	}
+
+	start := l.pos
+	switch {
+	case l.pos >= len(l.src):
+		return nil, ErrIncomplete
+	case isNameStart(l.src[l.pos]):
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+	case isDigit(l.src[l.pos]):
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	case isSpecialParam(l.src[l.pos]):
+		l.pos++
+	default:
+		return nil, bad
+	}
+	p.Name = l.src[start:l.pos]
+
+	if l.pos >= len(l.src) {
+		return nil, ErrIncomplete
+	}
+	if l.src[l.pos] == '}' {
+		l.pos++
+		return p, nil
+	}
+	if p.Length {
+		return nil, bad
+	}
+
+	opStart := l.pos
+	if l.src[l.pos] == ':' {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return nil, ErrIncomplete
+	}
+	if strings.IndexByte("-=?+", l.src[l.pos]) < 0 {
+		return nil, bad
+	}
+	l.pos++
+	p.Op = l.src[opStart:l.pos]
+
+	argStart := l.pos
+	parts, err := l.readParts(func(c byte) bool { return c == '}' })
+	if err != nil {
+		return nil, err
+	}
+	if l.pos >= len(l.src) {
+		return nil, ErrIncomplete
+	}
+	p.Arg = &Word{Raw: l.src[argStart:l.pos], Parts: parts}
+	l.pos++
+
+	return p, nil
+}
+
+// readHereDocs reads the bodies of the pending here-documents, one after
+// another, each up to its delimiter line.
+func (l *lexer) readHereDocs() error {
+	for _, r := range l.hereDocs {
+		delimiter, quoted := hereDocDelimiter(r.Target)
+
+		var body strings.Builder
+		for {
+			if l.pos >= len(l.src) {
+				return errHereDocEOF
+			}
+
+			line := l.src[l.pos:]
+			if i := strings.IndexByte(line, '\n'); i >= 0 {
+				line = line[:i]
+				l.pos++
+			}
+			l.pos += len(line)
+			l.line++
+
+			if r.Op == "<<-" {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == delimiter {
+				break
+			}
+			body.WriteString(line + "\n")
+		}
+
+		if quoted {
+			r.Body = &Word{Raw: body.String(), Parts: []WordPart{&Lit{Value: body.String(), Quoted: true}}}
+			continue
+		}
+
+		bodyLexer := &lexer{src: body.String(), line: l.line}
+		parts, err := bodyLexer.readDoubleQuoted(false)
+		if err != nil {
+			return err
+		}
+		r.Body = &Word{Raw: body.String(), Parts: parts}
+	}
+
+	l.hereDocs = nil
+	return nil
+}
+
+// hereDocDelimiter removes quotes from the delimiter word. Any quoting
+// means that the body is taken literally.
+func hereDocDelimiter(w *Word) (string, bool) {
+	var b strings.Builder
+	quoted := false
+
+	for _, part := range w.Parts {
+		lit, ok := part.(*Lit)
+		if !ok {
+			return w.Raw, false
+		}
+		b.WriteString(lit.Value)
+		quoted = quoted || lit.Quoted
+	}
+
+	return b.String(), quoted
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+func isSpecialParam(c byte) bool {
+	return strings.IndexByte("?$#@*!-", c) >= 0
+}
+
+// IsName reports whether s is a valid variable name.
+func IsName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,277 @@
+package parser
+
+import "strings"
+
+// Parse parses a script into its AST. It returns ErrIncomplete when src
+// ends where more input is expected, and a *SyntaxError when it is
+// malformed.
+func Parse(src string) (*List, error) {
+	p := &parser{lex: &lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+
+	return list, nil
+}
+
+type parser struct {
+	lex *lexer
+	tok token // the next token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+// isReserved reports whether the next token is the unquoted word w.
+func (p *parser) isReserved(w string) bool {
+	return p.tok.kind == tokWord && p.tok.text == w
+}
+
+func (p *parser) skipNewlines() error {
+	for p.tok.kind == tokNewline {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseList parses and-or lists up to the end of the input, a token that
+// cannot start a command or a reserved word that ends a compound command.
+func (p *parser) parseList() (*List, error) {
+	list := &List{}
+
+	for {
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokEOF || p.isOp(")") || p.isOp(";;") || p.atClosingWord() {
+			return list, nil
+		}
+
+		andOr, err := p.parseAndOr()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, andOr)
+
+		switch {
+		case p.isOp("&"):
+			andOr.Background = true
+			fallthrough
+		case p.isOp(";"), p.tok.kind == tokNewline:
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+		default:
+			return list, nil
+		}
+	}
+}
+
+func (p *parser) parseAndOr() (*AndOr, error) {
+	pipeline, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+	andOr := &AndOr{Pipelines: []*Pipeline{pipeline}}
+
+	for p.isOp("&&") || p.isOp("||") {
+		andOr.Ops = append(andOr.Ops, p.tok.text)
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if err = p.skipNewlines(); err != nil {
+			return nil, err
+		}
+
+		if pipeline, err = p.parsePipeline(); err != nil {
+			return nil, err
+		}
+		andOr.Pipelines = append(andOr.Pipelines, pipeline)
+	}
+
+	return andOr, nil
+}
+
+func (p *parser) parsePipeline() (*Pipeline, error) {
+	pipeline := &Pipeline{}
+	if p.isReserved("!") {
+		pipeline.Negate = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+
+		if !p.isOp("|") {
+			return pipeline, nil
+		}
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if err = p.skipNewlines(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseCommand parses a compound command, a function definition or a
+// simple command.
+func (p *parser) parseCommand() (Command, error) {
+	switch {
+	case p.isReserved("if"):
+		return p.parseIf()
+	case p.isReserved("while"), p.isReserved("until"):
+		return p.parseWhile()
+	case p.isReserved("for"):
+		return p.parseFor()
+	case p.isReserved("case"):
+		return p.parseCase()
+	case p.isReserved("{"):
+		return p.parseBraceGroup()
+	case p.atClosingWord():
+		return nil, unexpected(p.tok.line, p.tok.text)
+	}
+
+	cmd, err := p.parseSimpleCommand()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("(") && len(cmd.Words) == 1 && len(cmd.Assigns)+len(cmd.Redirects) == 0 {
+		return p.parseFuncDef(cmd.Words[0])
+	}
+	return cmd, nil
+}
+
+func (p *parser) parseSimpleCommand() (*SimpleCommand, error) {
+	cmd := &SimpleCommand{}
+
+	for {
+		switch {
+		case p.tok.kind == tokWord:
+			if assign := assignment(p.tok.word); assign != nil && len(cmd.Words) == 0 {
+				cmd.Assigns = append(cmd.Assigns, assign)
+			} else {
+				cmd.Words = append(cmd.Words, p.tok.word)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case p.tok.kind == tokOp && isRedirection(p.tok.text):
+			r, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			cmd.Redirects = append(cmd.Redirects, r)
+		case len(cmd.Assigns)+len(cmd.Words)+len(cmd.Redirects) > 0:
+			return cmd, nil
+		case p.tok.kind == tokEOF:
+			// An operator like | or && is waiting for its command.
+			return nil, ErrIncomplete
+		default:
+			return nil, unexpected(p.tok.line, p.tok.text)
+		}
+	}
+}
+
+func isRedirection(op string) bool {
+	switch op {
+	case "<", ">", ">>", "<&", ">&", "&>", "&>>", "<<", "<<-":
+		return true
+	}
+	return false
+}
+
+// parseRedirects parses the redirections after a compound command.
+func (p *parser) parseRedirects() ([]*Redirect, error) {
+	var redirects []*Redirect
+	for p.tok.kind == tokOp && isRedirection(p.tok.text) {
+		r, err := p.parseRedirect()
+		if err != nil {
+			return nil, err
+		}
+		redirects = append(redirects, r)
+	}
+	return redirects, nil
+}
+
+func (p *parser) parseRedirect() (*Redirect, error) {
+	op, fd := p.tok.text, p.tok.fd
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokWord {
+		text := p.tok.text
+		if p.tok.kind == tokEOF {
+			text = "newline"
+		}
+		return nil, unexpected(p.tok.line, text)
+	}
+
+	if fd < 0 {
+		fd = 1
+		if strings.HasPrefix(op, "<") {
+			fd = 0
+		}
+	}
+
+	r := &Redirect{Fd: fd, Op: op, Target: p.tok.word}
+	if op == "<<" || op == "<<-" {
+		// Registered before the next token is read, as that may be the
+		// newline after which the body starts.
+		p.lex.hereDocs = append(p.lex.hereDocs, r)
+	}
+
+	return r, p.advance()
+}
+
+// assignment returns the NAME=value assignment w stands for, or nil. The
+// name and the '=' must not be quoted.
+func assignment(w *Word) *Assign {
+	if len(w.Parts) == 0 {
+		return nil
+	}
+	lit, ok := w.Parts[0].(*Lit)
+	if !ok || lit.Quoted {
+		return nil
+	}
+
+	name, rest, found := strings.Cut(lit.Value, "=")
+	if !found || !IsName(name) {
+		return nil
+	}
+
+	value := &Word{Raw: w.Raw[len(name)+1:]}
+	if rest != "" {
+		value.Parts = append(value.Parts, &Lit{Value: rest})
+	}
+	value.Parts = append(value.Parts, w.Parts[1:]...)
+
+	return &Assign{Name: name, Value: value}
+}
@@ -0,0 +1,279 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mapEnv is an Env backed by a map.
+type mapEnv map[string]string
+
+func (m mapEnv) Get(name string) (string, bool) {
+	value, ok := m[name]
+	return value, ok
+}
+
+func (m mapEnv) Set(name, value string) error {
+	m[name] = value
+	return nil
+}
+
+// Positional collects the entries "1", "2" and so on.
+func (m mapEnv) Positional() []string {
+	var args []string
+	for i := 1; ; i++ {
+		arg, ok := m[strconv.Itoa(i)]
+		if !ok {
+			return args
+		}
+		args = append(args, arg)
+	}
+}
+
+// words parses src as a single simple command and expands its words.
+func words(t *testing.T, src string, env Env) []string {
+	t.Helper()
+
+	list, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(list.Items) != 1 || len(list.Items[0].Pipelines) != 1 || len(list.Items[0].Pipelines[0].Commands) != 1 {
+		t.Fatalf("Expected a single command in %q", src)
+	}
+
+	var result []string
+	for _, w := range list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand).Words {
+		fields, err := Fields(w, env)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result = append(result, fields...)
+	}
+	return result
+}
+
+func TestQuoting(t *testing.T) {
+	env := mapEnv{"HOME": "/home/user", "X": "a  b", "EMPTY": ""}
+
+	tests := []struct {
+		src      string
+		expected []string
+	}{
+		{`echo "a   b"`, []string{"echo", "a   b"}},
+		{`echo 'single $X quotes'`, []string{"echo", "single $X quotes"}},
+		{`echo \$X \"`, []string{"echo", "$X", `"`}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo "a\$b \q \\"`, []string{"echo", `a$b \q \`}},
+		{`echo $X`, []string{"echo", "a", "b"}},
+		{`echo "$X"`, []string{"echo", "a  b"}},
+		{`echo pre"$X"post`, []string{"echo", "prea  bpost"}},
+		{`echo $EMPTY "" ''`, []string{"echo", "", ""}},
+		{`echo $UNSET`, []string{"echo"}},
+		{`echo ~ ~/bin a~`, []string{"echo", "/home/user", "/home/user/bin", "a~"}},
+		{`echo "~"`, []string{"echo", "~"}},
+		{`echo $ a$ "$"`, []string{"echo", "$", "a$", "$"}},
+		{"echo 'multi\nline'", []string{"echo", "multi\nline"}},
+		{"echo a\\\nb", []string{"echo", "ab"}},
+		{`echo a#b # comment`, []string{"echo", "a#b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			if got := words(t, tt.src, env); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseStructure(t *testing.T) {
+	list, err := Parse("a | b && ! c || d; e\n\nf 2>&1 >out <<-EOF\n\tbody $x\n\tEOF\nX=1 Y=\"2 3\" g x=4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(list.Items) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(list.Items))
+	}
+
+	first := list.Items[0]
+	if !reflect.DeepEqual(first.Ops, []string{"&&", "||"}) {
+		t.Errorf("Expected ops [&& ||], got %v", first.Ops)
+	}
+	if len(first.Pipelines[0].Commands) != 2 {
+		t.Errorf("Expected a two-stage pipeline, got %d stages", len(first.Pipelines[0].Commands))
+	}
+	if !first.Pipelines[1].Negate || first.Pipelines[0].Negate {
+		t.Errorf("Expected only the second pipeline to be negated")
+	}
+
+	redirects := list.Items[2].Pipelines[0].Commands[0].(*SimpleCommand).Redirects
+	if len(redirects) != 3 {
+		t.Fatalf("Expected 3 redirections, got %d", len(redirects))
+	}
+	for i, expected := range []struct {
+		fd int
+		op string
+	}{{2, ">&"}, {1, ">"}, {0, "<<-"}} {
+		if redirects[i].Fd != expected.fd || redirects[i].Op != expected.op {
+			t.Errorf("Expected redirection %d%s, got %d%s", expected.fd, expected.op, redirects[i].Fd, redirects[i].Op)
+		}
+	}
+	body, err := Expand(redirects[2].Body, mapEnv{"x": "X"})
+	if err != nil || body != "body X\n" {
+		t.Errorf("Expected here-document body %q, got %q (%v)", "body X\n", body, err)
+	}
+
+	last := list.Items[3].Pipelines[0].Commands[0].(*SimpleCommand)
+	if len(last.Assigns) != 2 || last.Assigns[0].Name != "X" || last.Assigns[1].Name != "Y" {
+		t.Fatalf("Expected assignments to X and Y, got %+v", last.Assigns)
+	}
+	if value, _ := Expand(last.Assigns[1].Value, mapEnv{}); value != "2 3" {
+		t.Errorf("Expected Y to be %q, got %q", "2 3", value)
+	}
+	if len(last.Words) != 2 || last.Words[1].Raw != "x=4" {
+		t.Errorf("Expected x=4 after the command name to be a word, got %+v", last.Words)
+	}
+}
+
+func TestParseCompound(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"if a; then b; fi", "if a; then b; fi"},
+		{"if a\nthen b\nelif c; then d\nelse e; fi > out", "if a; then b; elif c; then d; else e; fi >out"},
+		{"while a && b; do c; done", "while a && b; do c; done"},
+		{"until a; do b; done < in", "until a; do b; done <in"},
+		{"for x in a 'b c'; do echo $x; done", "for x in a 'b c'; do echo $x; done"},
+		{"for x\ndo echo; done", "for x; do echo; done"},
+		{"for x in; do y; done", "for x in; do y; done"},
+		{"case $x in\n(a|b) one;;\n*.go) two; three\n;;\nesac", "case $x in a | b) one;; *.go) two; three;; esac"},
+		{"case x in a) ;; b) c\nesac", "case x in a);; b) c;; esac"},
+		{"{ a; b; } 2>&1", "{ a; b; } 2>&1"},
+		{"f() { echo $1; }", "f() { echo $1; }"},
+		{"f()\n{\necho\n}", "f() { echo; }"},
+		{"if a; then for i in 1; do b & done; fi | c", "if a; then for i in 1; do b & done; fi | c"},
+		{"echo if then fi done", "echo if then fi done"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			list, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := list.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQuotedHereDocDelimiter(t *testing.T) {
+	list, err := Parse("cat <<'EOF'\n$HOME `x`\nEOF\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand).Redirects[0]
+	if body, _ := Expand(r.Body, mapEnv{"HOME": "/root"}); body != "$HOME `x`\n" {
+		t.Errorf("Expected a literal body, got %q", body)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	incomplete := []string{
+		`echo "abc`, `echo 'abc`, `echo abc\`, "ls |", "ls &&", "ls ||\n\n",
+		"cat <<EOF", "cat <<EOF\nbody", "echo ${X", "echo ${X:-a",
+		"if a", "if a; then", "if a; then b", "if a; then b; else", "while a; do b",
+		"for", "for x in a b", "for x in a b; do", "case x", "case x in", "case x in a",
+		"case x in a) b", "{ a", "{ a }", "f()", "f() {",
+	}
+	for _, src := range incomplete {
+		if _, err := Parse(src); !errors.Is(err, ErrIncomplete) {
+			t.Errorf("Expected ErrIncomplete for %q, got %v", src, err)
+		}
+	}
+
+	syntax := map[string]string{
+		"| ls":              "`|'",
+		"ls | | wc":         "`|'",
+		"ls && ; x":         "`;'",
+		"echo >":            "`newline'",
+		"echo > | wc":       "`|'",
+		"echo a\n)":         "line 2",
+		"echo ${}":          "bad substitution",
+		"echo ${X%y}":       "bad substitution",
+		"echo $(date)":      "not supported",
+		"echo `date`":       "not supported",
+		"if a; fi":          "`fi'",
+		"if; then b; fi":    "`;'",
+		"if a; then fi":     "`fi'",
+		"while a; done":     "`done'",
+		"for 1 in a":        "`1'",
+		"for x y":           "`y'",
+		"case x y":          "`y'",
+		"case x in a; esac": "`;'",
+		"{ }":               "`}'",
+		"a; fi":             "`fi'",
+		"true && done":      "`done'",
+		"f() echo":          "`echo'",
+		"a-b() { x; }":      "`('",
+		"echo a;;":          "`;;'",
+	}
+	for src, expected := range syntax {
+		_, err := Parse(src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected a syntax error for %q, got %v", src, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error for %q to contain %q, got %q", src, expected, err)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"echo hello", `echo "a $b ${c:-d}" 'e'`, "a | b && c || d; e",
+		"cat <<EOF\nbody\nEOF", "x=1 y=${#z} cmd 2>&1 >>out", `\"\$`,
+		"if a; then b; elif c; then d; else e; fi", "for x in a b; do c; done | d",
+		"case $x in a|b) c;; *) d;; esac", "f() { while a; do b; done; }",
+	} {
+		f.Add(seed)
+	}
+
+	env := mapEnv{"HOME": "/root"}
+	f.Fuzz(func(t *testing.T, src string) {
+		list, err := Parse(src)
+		if err != nil {
+			var syntaxErr *SyntaxError
+			if !errors.Is(err, ErrIncomplete) && !errors.As(err, &syntaxErr) {
+				t.Fatalf("Unexpected error type %T: %v", err, err)
+			}
+			return
+		}
+
+		// Expansion may fail, ${x?} does, but must not panic.
+		for _, item := range list.Items {
+			for _, p := range item.Pipelines {
+				for _, c := range p.Commands {
+					simple, ok := c.(*SimpleCommand)
+					if !ok {
+						_ = c.String()
+						continue
+					}
+					for _, w := range simple.Words {
+						_, _ = Fields(w, env)
+					}
+				}
+			}
+		}
+	})
+}
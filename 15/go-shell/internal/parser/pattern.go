@@ -0,0 +1,133 @@
+package parser
+
+import "strings"
+
+// Pattern expands w into a pattern for Match, as done for the patterns of
+// case. Quoted characters, and those of quoted expansions, are escaped so
+// that they only match themselves.
+func Pattern(w *Word, env Env) (string, error) {
+	var b strings.Builder
+	for i, part := range w.Parts {
+		switch part := part.(type) {
+		case *Lit:
+			switch {
+			case part.Quoted:
+				b.WriteString(escapePattern(part.Value))
+			case i == 0:
+				b.WriteString(expandTilde(part.Value, env))
+			default:
+				b.WriteString(part.Value)
+			}
+		case *Param:
+			value, err := expandParam(part, env)
+			if err != nil {
+				return "", err
+			}
+			if part.Quoted {
+				value = escapePattern(value)
+			}
+			b.WriteString(value)
+		}
+	}
+	return b.String(), nil
+}
+
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Match reports whether s matches the glob pattern: * matches any string,
+// ? any character, [abc], [a-z] and [!a-z] a character of a set, and \
+// quotes the character after it. Unlike path.Match, * also matches '/'.
+func Match(pattern, s string) bool {
+	return match([]rune(pattern), []rune(s))
+}
+
+func match(pattern, s []rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			for i := 0; i <= len(s); i++ {
+				if match(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if matched, n, ok := matchClass(pattern, s); ok {
+				if !matched {
+					return false
+				}
+				pattern, s = pattern[n:], s[1:]
+				continue
+			}
+			// An unclosed [ is an ordinary character.
+			if len(s) == 0 || s[0] != '[' {
+				return false
+			}
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchClass matches the first character of s against the bracket
+// expression that pattern starts with. It returns the length of the
+// expression, and ok false when it is not closed.
+func matchClass(pattern, s []rune) (matched bool, n int, ok bool) {
+	i := 1
+	negate := i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^')
+	if negate {
+		i++
+	}
+
+	for first := true; i < len(pattern); first = false {
+		if pattern[i] == ']' && !first {
+			return len(s) > 0 && matched != negate, i + 1, true
+		}
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			i += 2
+			hi = pattern[i]
+			if hi == '\\' && i+1 < len(pattern) {
+				i++
+				hi = pattern[i]
+			}
+		}
+		i++
+
+		if len(s) > 0 && lo <= s[0] && s[0] <= hi {
+			matched = true
+		}
+	}
+	return false, 0, false
+}
@@ -0,0 +1,66 @@
+package parser
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{"*", "", true},
+		{"*", "a/b", true},
+		{"*.go", "main.go", true},
+		{"*.go", "main.go.bak", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"?", "ü", true},
+		{"??", "a", false},
+		{"[abc]x", "bx", true},
+		{"[a-c]", "d", false},
+		{"[!a-c]", "d", true},
+		{"[^a-c]", "a", false},
+		{"[]]", "]", true},
+		{"[!]]", "]", false},
+		{"[a-]", "-", true},
+		{"[", "[", true},
+		{"[ab", "a", false},
+		{`\*`, "*", true},
+		{`\*`, "x", false},
+		{`a\`, `a\`, true},
+		{"", "", true},
+		{"", "a", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.s); got != tt.expected {
+			t.Errorf("Expected Match(%q, %q) to be %v, got %v", tt.pattern, tt.s, tt.expected, got)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`*.go`, `*.go`},
+		{`"*".go`, `\*.go`},
+		{`'[a]'?`, `\[a\]?`},
+		{`$P`, `a*`},
+		{`"$P"`, `a\*`},
+		{`~/x*`, `/home/user/x*`},
+	}
+
+	env := mapEnv{"P": "a*", "HOME": "/home/user"}
+	for _, tt := range tests {
+		list, err := Parse("echo " + tt.src)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w := list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand).Words[1]
+		if got, err := Pattern(w, env); err != nil || got != tt.expected {
+			t.Errorf("Expected %q for %s, got %q (%v)", tt.expected, tt.src, got, err)
+		}
+	}
+}
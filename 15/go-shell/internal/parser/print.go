@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// String returns the list as source text on one line.
+func (l *List) String() string {
+	var b strings.Builder
+	for i, andOr := range l.Items {
+		if i > 0 {
+			if l.Items[i-1].Background {
+				b.WriteString(" ")
+			} else {
+				b.WriteString("; ")
+			}
+		}
+		b.WriteString(andOr.String())
+	}
+	return b.String()
+}
+
+// terminated is the list followed by the ; or the space after & that
+// separates it from a reserved word.
+func (l *List) terminated() string {
+	if n := len(l.Items); n > 0 && l.Items[n-1].Background {
+		return l.String() + " "
+	}
+	return l.String() + "; "
+}
+
+func (a *AndOr) String() string {
+	text := a.Pipelines[0].String()
+	for i, op := range a.Ops {
+		text += " " + op + " " + a.Pipelines[i+1].String()
+	}
+	if a.Background {
+		text += " &"
+	}
+	return text
+}
+
+// String returns the pipeline as source text, as shown by jobs.
+func (p *Pipeline) String() string {
+	var commands []string
+	for _, c := range p.Commands {
+		commands = append(commands, c.String())
+	}
+
+	text := strings.Join(commands, " | ")
+	if p.Negate {
+		text = "! " + text
+	}
+	return text
+}
+
+func (c *SimpleCommand) String() string {
+	var words []string
+	for _, a := range c.Assigns {
+		words = append(words, a.Name+"="+a.Value.Raw)
+	}
+	for _, w := range c.Words {
+		words = append(words, w.Raw)
+	}
+	for _, r := range c.Redirects {
+		words = append(words, r.String())
+	}
+	return strings.Join(words, " ")
+}
+
+func (c *IfClause) String() string {
+	var b strings.Builder
+	for i, cond := range c.Conds {
+		if i > 0 {
+			b.WriteString("el")
+		}
+		b.WriteString("if " + cond.terminated() + "then " + c.Thens[i].terminated())
+	}
+	if c.Else != nil {
+		b.WriteString("else " + c.Else.terminated())
+	}
+	b.WriteString("fi")
+	return b.String() + redirects(c.Redirects)
+}
+
+func (c *WhileClause) String() string {
+	keyword := "while "
+	if c.Until {
+		keyword = "until "
+	}
+	return keyword + c.Cond.terminated() + "do " + c.Body.terminated() + "done" + redirects(c.Redirects)
+}
+
+func (c *ForClause) String() string {
+	text := "for " + c.Name
+	if !c.Positional {
+		text += " in"
+		for _, w := range c.Items {
+			text += " " + w.Raw
+		}
+	}
+	return text + "; do " + c.Body.terminated() + "done" + redirects(c.Redirects)
+}
+
+func (c *CaseClause) String() string {
+	var b strings.Builder
+	b.WriteString("case " + c.Word.Raw + " in")
+	for _, item := range c.Items {
+		patterns := make([]string, len(item.Patterns))
+		for i, w := range item.Patterns {
+			patterns[i] = w.Raw
+		}
+		b.WriteString(" " + strings.Join(patterns, " | ") + ")")
+		if item.Body != nil && len(item.Body.Items) > 0 {
+			b.WriteString(" " + item.Body.String())
+		}
+		b.WriteString(";;")
+	}
+	b.WriteString(" esac")
+	return b.String() + redirects(c.Redirects)
+}
+
+func (c *BraceGroup) String() string {
+	return "{ " + c.List.terminated() + "}" + redirects(c.Redirects)
+}
+
+func (c *FuncDef) String() string {
+	return c.Name + "() " + c.Body.String()
+}
+
+func redirects(list []*Redirect) string {
+	var text string
+	for _, r := range list {
+		text += " " + r.String()
+	}
+	return text
+}
+
+// String omits the descriptor where it is the default one.
+func (r *Redirect) String() string {
+	fd := strconv.Itoa(r.Fd)
+	if (r.Fd == 0 && strings.HasPrefix(r.Op, "<")) || (r.Fd == 1 && !strings.HasPrefix(r.Op, "<")) {
+		fd = ""
+	}
+	return fd + r.Op + r.Target.Raw
+}
@@ -0,0 +1,261 @@
+// Package procfs reads processes from a Linux proc filesystem. The root
+// directory is configurable, so that tests can use a fixture tree.
+package procfs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultRoot is where the proc filesystem is usually mounted.
+const DefaultRoot = "/proc"
+
+// ClockTicks is USER_HZ, the unit of the times in /proc/<pid>/stat. Linux
+// keeps it at 100 for userspace on every architecture.
+const ClockTicks = 100
+
+var errMalformed = errors.New("malformed")
+
+// FS is a proc filesystem mounted at Root.
+type FS struct {
+	Root string
+}
+
+// Process is what ps needs to know about one process.
+type Process struct {
+	PID, PPID int
+	PGID, SID int
+	TPGID     int    // foreground process group of its terminal
+	TTY       int    // device number of its terminal, 0 for none
+	State     string // R, S, D, Z, T, ...
+	Nice      int
+	Threads   int
+
+	UTime, STime uint64 // CPU time in clock ticks
+	StartTime    uint64 // clock ticks after boot
+
+	UID      int
+	VSZ, RSS uint64 // kB
+
+	Comm    string
+	Cmdline []string // empty for kernel threads
+}
+
+// System holds the machine-wide values that relative columns, like
+// %CPU and %MEM, are computed from.
+type System struct {
+	Uptime   time.Duration
+	BootTime time.Time
+	MemTotal uint64 // kB
+}
+
+// Processes returns every process, ordered by PID. Processes that exit
+// while they are being read are left out.
+func (p FS) Processes() ([]Process, error) {
+	entries, err := os.ReadDir(p.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		process, err := p.Process(pid)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		processes = append(processes, process)
+	}
+
+	// Directory order is by name, which puts 10 before 9.
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+	return processes, nil
+}
+
+// Process reads /proc/<pid>/stat, status and cmdline.
+func (p FS) Process(pid int) (Process, error) {
+	dir := filepath.Join(p.Root, strconv.Itoa(pid))
+	process := Process{PID: pid}
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return process, err
+	}
+	if err = parseStat(string(stat), &process); err != nil {
+		return process, fmt.Errorf("%s: %w", filepath.Join(dir, "stat"), err)
+	}
+
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return process, err
+	}
+	if err = parseStatus(status, &process); err != nil {
+		return process, fmt.Errorf("%s: %w", filepath.Join(dir, "status"), err)
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return process, err
+	}
+	if cmdline = bytes.TrimRight(cmdline, "\x00"); len(cmdline) > 0 {
+		process.Cmdline = strings.Split(string(cmdline), "\x00")
+	}
+
+	return process, nil
+}
+
+// parseStat parses "pid (comm) state ppid ...". comm may hold spaces and
+// parentheses itself, so it ends at the last ')'.
+func parseStat(stat string, process *Process) error {
+	open, closing := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return errMalformed
+	}
+	process.Comm = stat[open+1 : closing]
+
+	// fields[0] is field 3 of proc(5), the state.
+	fields := strings.Fields(stat[closing+1:])
+	if len(fields) < 22 {
+		return errMalformed
+	}
+	process.State = fields[0]
+
+	ints := []*int{&process.PPID, &process.PGID, &process.SID, &process.TTY, &process.TPGID}
+	for i, dst := range ints {
+		n, err := strconv.Atoi(fields[1+i])
+		if err != nil {
+			return errMalformed
+		}
+		*dst = n
+	}
+
+	var err error
+	parse := func(i int) uint64 {
+		n, parseErr := strconv.ParseUint(fields[i], 10, 64)
+		err = errors.Join(err, parseErr)
+		return n
+	}
+	process.UTime, process.STime = parse(11), parse(12)
+	process.StartTime = parse(19)
+
+	nice, niceErr := strconv.Atoi(fields[16])
+	threads, threadsErr := strconv.Atoi(fields[17])
+	if err = errors.Join(err, niceErr, threadsErr); err != nil {
+		return errMalformed
+	}
+	process.Nice, process.Threads = nice, threads
+
+	return nil
+}
+
+// parseStatus takes the owner and the memory sizes from status. Kernel
+// threads have no Vm lines, and their sizes stay 0.
+func parseStatus(status []byte, process *Process) error {
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "Uid":
+			process.UID, err = strconv.Atoi(fields[0])
+		case "VmSize":
+			process.VSZ, err = strconv.ParseUint(fields[0], 10, 64)
+		case "VmRSS":
+			process.RSS, err = strconv.ParseUint(fields[0], 10, 64)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, errMalformed)
+		}
+	}
+	return scanner.Err()
+}
+
+// System reads uptime, the boot time from stat and MemTotal from meminfo.
+func (p FS) System() (System, error) {
+	var system System
+
+	uptime, err := os.ReadFile(filepath.Join(p.Root, "uptime"))
+	if err != nil {
+		return system, err
+	}
+	fields := strings.Fields(string(uptime))
+	if len(fields) == 0 {
+		return system, fmt.Errorf("uptime: %w", errMalformed)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return system, fmt.Errorf("uptime: %w", errMalformed)
+	}
+	system.Uptime = time.Duration(seconds * float64(time.Second))
+
+	btime, err := readField(filepath.Join(p.Root, "stat"), "btime")
+	if err != nil {
+		return system, err
+	}
+	system.BootTime = time.Unix(int64(btime), 0)
+
+	system.MemTotal, err = readField(filepath.Join(p.Root, "meminfo"), "MemTotal:")
+	return system, err
+}
+
+// readField returns the number after key in a file of "key value" lines.
+func readField(path, key string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == key {
+			n, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				break
+			}
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %s: %w", path, strings.TrimSuffix(key, ":"), errMalformed)
+}
+
+// TTYName names a terminal device number the way ps does, "pts/3" or
+// "tty1", and returns "?" for none or an unknown one.
+func TTYName(dev int) string {
+	major, minor := (dev>>8)&0xfff, (dev&0xff)|((dev>>12)&0xfff00)
+	switch {
+	case dev == 0:
+		return "?"
+	case major >= 136 && major <= 143:
+		return "pts/" + strconv.Itoa((major-136)*256+minor)
+	case major == 4 && minor < 64:
+		return "tty" + strconv.Itoa(minor)
+	case major == 4:
+		return "ttyS" + strconv.Itoa(minor-64)
+	}
+	return "?"
+}
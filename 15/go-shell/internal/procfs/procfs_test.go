@@ -0,0 +1,89 @@
+package procfs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var fixture = FS{Root: "testdata/proc"}
+
+func TestProcesses(t *testing.T) {
+	processes, err := fixture.Processes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var pids []int
+	for _, p := range processes {
+		pids = append(pids, p.PID)
+	}
+	// 300 has vanished half-way and self is no process.
+	if expected := []int{1, 2, 100, 200, 201}; !reflect.DeepEqual(pids, expected) {
+		t.Errorf("Expected PIDs %v, got %v", expected, pids)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		pid      int
+		expected Process
+	}{
+		{
+			pid: 201,
+			expected: Process{
+				PID: 201, PPID: 100, PGID: 201, SID: 100, TPGID: 200, TTY: 34816,
+				State: "R", Nice: 5, Threads: 4,
+				UTime: 6000, STime: 3000, StartTime: 900000,
+				UID: 1000, VSZ: 500000, RSS: 240000,
+				Comm: "my (odd) prog", Cmdline: []string{"./prog", "--flag"},
+			},
+		},
+		{
+			pid: 2,
+			expected: Process{
+				PID: 2, TPGID: -1, State: "S", Threads: 1, STime: 2, StartTime: 10,
+				Comm: "kthreadd",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		process, err := fixture.Process(tt.pid)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(process, tt.expected) {
+			t.Errorf("Expected %+v, got %+v", tt.expected, process)
+		}
+	}
+}
+
+func TestParseStatErrors(t *testing.T) {
+	for _, stat := range []string{"", "1 (x", "1 (x) S 0", "1 (x) S a 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 10 0 0"} {
+		if err := parseStat(stat, &Process{}); err == nil {
+			t.Errorf("Expected an error for %q", stat)
+		}
+	}
+}
+
+func TestSystem(t *testing.T) {
+	system, err := fixture.System()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := System{Uptime: 10000 * time.Second, BootTime: time.Unix(1700000000, 0), MemTotal: 16000000}
+	if system != expected {
+		t.Errorf("Expected %+v, got %+v", expected, system)
+	}
+}
+
+func TestTTYName(t *testing.T) {
+	tests := map[int]string{0: "?", 34816: "pts/0", 34819: "pts/3", 1025: "tty1", 1089: "ttyS1", 1234567: "?"}
+	for dev, expected := range tests {
+		if name := TTYName(dev); name != expected {
+			t.Errorf("Expected %q for %d, got %q", expected, dev, name)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"wb-tech-l2/15/go-shell/internal/handler"
+	"wb-tech-l2/15/go-shell/internal/parser"
+)
+
+// options are the shell settings changed with set. monitor, job control,
+// is on in interactive shells.
+type options struct {
+	errexit  bool
+	nounset  bool
+	pipefail bool
+	xtrace   bool
+	monitor  bool
+}
+
+// optionNames are the options set -o and +o take, with the letters of
+// their short forms.
+var optionNames = []struct {
+	name   string
+	letter byte
+}{
+	{"errexit", 'e'},
+	{"nounset", 'u'},
+	{"pipefail", 0},
+	{"xtrace", 'x'},
+}
+
+func (o *options) lookup(name string) *bool {
+	switch name {
+	case "errexit", "e":
+		return &o.errexit
+	case "nounset", "u":
+		return &o.nounset
+	case "pipefail":
+		return &o.pipefail
+	case "xtrace", "x":
+		return &o.xtrace
+	}
+	return nil
+}
+
+// flags is $-, the letters of the options that are on.
+func (o *options) flags() string {
+	var b strings.Builder
+	for _, opt := range optionNames {
+		if opt.letter != 0 && *o.lookup(opt.name) {
+			b.WriteByte(opt.letter)
+		}
+	}
+	if o.monitor {
+		b.WriteByte('m')
+	}
+	return b.String()
+}
+
+// builtin returns the commands that change the shell itself. They are
+// looked up before the handler built-ins.
+func (s *Shell) builtin(name string) (func(args []string, w io.Writer) error, bool) {
+	switch name {
+	case "cd":
+		return s.cd, true
+	case "pwd":
+		return s.pwd, true
+	case "set":
+		return s.set, true
+	case "export":
+		return s.exportBuiltin, true
+	case "unset":
+		return s.unsetBuiltin, true
+	case "jobs":
+		return s.jobsBuiltin, true
+	case "fg":
+		return s.fg, true
+	case "bg":
+		return s.bg, true
+	case "wait":
+		return s.wait, true
+	case "kill":
+		return s.kill, true
+	case "ps":
+		return s.ps, true
+	case "source", ".":
+		return s.source, true
+	case "exit":
+		return s.exit, true
+	case "shift":
+		return s.shift, true
+	case "local":
+		return s.localBuiltin, true
+	case "break":
+		return s.breakBuiltin, true
+	case "continue":
+		return s.continueBuiltin, true
+	case "return":
+		return s.returnBuiltin, true
+	}
+	return nil, false
+}
+
+// cd changes the working directory. A subshell changes only its own, so
+// that cd in a pipeline or in the background leaves the shell's alone.
+func (s *Shell) cd(args []string, w io.Writer) error {
+	if s.dir == "" {
+		return handler.HandleCommand("cd", args, w)
+	}
+	dir, err := handler.ResolveDir(s.dir, args)
+	if err != nil {
+		return err
+	}
+	s.dir = dir
+	return nil
+}
+
+func (s *Shell) pwd(args []string, w io.Writer) error {
+	if s.dir == "" {
+		return handler.HandleCommand("pwd", args, w)
+	}
+	_, err := fmt.Fprintln(w, s.dir)
+	return err
+}
+
+// ps is the handler's ps, reading the proc filesystem the shell is set to.
+func (s *Shell) ps(args []string, w io.Writer) error {
+	return handler.Ps(args, w, s.procRoot)
+}
+
+// path resolves a file name against the working directory of a subshell.
+func (s *Shell) path(name string) string {
+	if s.dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+// set turns options on with -e, -u, -x or -o name, and off with +e, +o
+// name and so on. The remaining arguments, or all after --, replace the
+// positional parameters. Without arguments it lists the options.
+func (s *Shell) set(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		for _, opt := range optionNames {
+			if _, err := fmt.Fprintf(w, "%s\t%s\n", opt.name, onOff(*s.options.lookup(opt.name))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			s.args = append([]string(nil), args[i+1:]...)
+			return nil
+		case arg == "-o" || arg == "+o":
+			if i+1 == len(args) {
+				return fmt.Errorf("set: %s: %w", arg, errInvalidOption)
+			}
+			i++
+			opt := s.options.lookup(args[i])
+			if opt == nil || len(args[i]) == 1 {
+				return fmt.Errorf("set: %s: %w", args[i], errInvalidOption)
+			}
+			*opt = arg == "-o"
+		case len(arg) > 1 && (arg[0] == '-' || arg[0] == '+'):
+			for _, letter := range arg[1:] {
+				opt := s.options.lookup(string(letter))
+				if opt == nil {
+					return fmt.Errorf("set: %c%c: %w", arg[0], letter, errInvalidOption)
+				}
+				*opt = arg[0] == '-'
+			}
+		default:
+			s.args = append([]string(nil), args[i:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// exportBuiltin exports NAME or NAME=value. Without arguments it lists
+// the exported variables.
+func (s *Shell) exportBuiltin(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		for _, kv := range s.exported() {
+			if _, err := fmt.Fprintf(w, "export %s\n", kv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !parser.IsName(name) {
+			return fmt.Errorf("export: `%s': %w", arg, errInvalidName)
+		}
+		if hasValue {
+			if err := s.Set(name, value); err != nil {
+				return err
+			}
+		}
+		if err := s.export(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Shell) unsetBuiltin(args []string, _ io.Writer) error {
+	for _, name := range args {
+		if !parser.IsName(name) {
+			return fmt.Errorf("unset: `%s': %w", name, errInvalidName)
+		}
+		if err := s.unset(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localBuiltin declares NAME or NAME=value local to the running function.
+func (s *Shell) localBuiltin(args []string, _ io.Writer) error {
+	if len(s.frames) == 0 {
+		return fmt.Errorf("local: %w", errNotInFunction)
+	}
+
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !parser.IsName(name) {
+			return fmt.Errorf("local: `%s': %w", arg, errInvalidName)
+		}
+		s.local(name)
+		if hasValue {
+			if err := s.Set(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
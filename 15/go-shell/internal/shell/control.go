@@ -0,0 +1,361 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"wb-tech-l2/15/go-shell/internal/handler"
+	"wb-tech-l2/15/go-shell/internal/parser"
+)
+
+// jump is a pending break, continue or return. The lists it passes on the
+// way up stop running until the loop or function it is for. An interrupt
+// is for the prompt, and stops everything up to it.
+type jump int
+
+const (
+	noJump jump = iota
+	breakJump
+	continueJump
+	returnJump
+	interruptJump
+)
+
+// runCompound runs a compound command, or defines a function, in the
+// shell itself.
+func (s *Shell) runCompound(c parser.Command) {
+	switch c := c.(type) {
+	case *parser.FuncDef:
+		if s.funcs == nil {
+			s.funcs = make(map[string]parser.Command)
+		}
+		s.funcs[c.Name] = c.Body
+		s.status = 0
+	case *parser.IfClause:
+		s.redirected(c.Redirects, func() { s.runIf(c) })
+	case *parser.WhileClause:
+		s.redirected(c.Redirects, func() { s.runWhile(c) })
+	case *parser.ForClause:
+		s.redirected(c.Redirects, func() { s.runFor(c) })
+	case *parser.CaseClause:
+		s.redirected(c.Redirects, func() { s.runCase(c) })
+	case *parser.BraceGroup:
+		s.redirected(c.Redirects, func() { s.runList(c.List) })
+	}
+}
+
+// redirected runs a compound command with its redirections applied to
+// the shell's own stdin, stdout and stderr.
+func (s *Shell) redirected(redirects []*parser.Redirect, run func()) {
+	if len(redirects) == 0 {
+		run()
+		return
+	}
+
+	var stage handler.Stage
+	for _, r := range redirects {
+		list, err := s.redirection(r)
+		if err != nil {
+			s.expansionError(err)
+			return
+		}
+		stage.Redirects = append(stage.Redirects, list...)
+	}
+
+	std, err := stage.Open(s.stdio())
+	if err != nil {
+		_ = handler.Report(std.Err, err)
+		s.status = 1
+		return
+	}
+	defer func() { _ = std.Close() }()
+
+	s.withStdio(std, run)
+}
+
+func (s *Shell) withStdio(std handler.Stdio, run func()) {
+	saved := s.stdio()
+	s.stdin, s.stdout, s.stderr = std.In, std.Out, std.Err
+	defer func() { s.stdin, s.stdout, s.stderr = saved.In, saved.Out, saved.Err }()
+	run()
+}
+
+// condition runs the condition of if, while or until, where set -e does
+// not apply, and reports whether it succeeded.
+func (s *Shell) condition(list *parser.List) bool {
+	s.tested++
+	defer func() { s.tested-- }()
+
+	s.runList(list)
+	return s.status == 0
+}
+
+// runIf runs the branch of the first condition that succeeds. Its status
+// is that of the branch, or 0 when no branch runs.
+func (s *Shell) runIf(c *parser.IfClause) {
+	for i, cond := range c.Conds {
+		ok := s.condition(cond)
+		if s.exited || s.jump != noJump {
+			return
+		}
+		if ok {
+			s.runList(c.Thens[i])
+			return
+		}
+	}
+
+	s.status = 0
+	if c.Else != nil {
+		s.runList(c.Else)
+	}
+}
+
+// runWhile runs the body for as long as the condition succeeds, or with
+// until fails. Its status is that of the last run of the body, 0 if there
+// was none.
+func (s *Shell) runWhile(c *parser.WhileClause) {
+	s.loops++
+	defer func() { s.loops-- }()
+
+	status := 0
+	for {
+		ok := s.condition(c.Cond)
+		if s.exited || s.jump != noJump {
+			if s.next() {
+				continue
+			}
+			break
+		}
+		if ok == c.Until {
+			break
+		}
+
+		s.runList(c.Body)
+		status = s.status
+		if !s.next() {
+			break
+		}
+	}
+	s.loopStatus(status)
+}
+
+// runFor runs the body once for every field of the words, or for every
+// positional parameter.
+func (s *Shell) runFor(c *parser.ForClause) {
+	items := s.args
+	if !c.Positional {
+		items = nil
+		for _, w := range c.Items {
+			fields, err := parser.Fields(w, s)
+			if err != nil {
+				s.expansionError(err)
+				return
+			}
+			items = append(items, fields...)
+		}
+	}
+
+	s.loops++
+	defer func() { s.loops-- }()
+
+	status := 0
+	for _, item := range items {
+		if err := s.Set(c.Name, item); err != nil {
+			_ = handler.Report(s.stderr, err)
+			s.status = 1
+			return
+		}
+
+		s.runList(c.Body)
+		status = s.status
+		if !s.next() {
+			break
+		}
+	}
+	s.loopStatus(status)
+}
+
+// loopStatus sets the status of a finished loop, unless exit, return or
+// an interrupt has set it already.
+func (s *Shell) loopStatus(status int) {
+	if !s.exited && s.jump != returnJump && s.jump != interruptJump {
+		s.status = status
+	}
+}
+
+// next settles a pending break or continue after an iteration and
+// reports whether the loop goes on. break n and continue n also end the
+// loop when n > 1, and leave the rest of the jump to the loops around.
+func (s *Shell) next() bool {
+	switch {
+	case s.exited || s.jump == returnJump || s.jump == interruptJump:
+		return false
+	case s.jump == noJump:
+		return true
+	case s.jumpCount > 1:
+		s.jumpCount--
+		return false
+	}
+
+	goOn := s.jump == continueJump
+	s.jump = noJump
+	return goOn
+}
+
+// runCase runs the list of the first item with a pattern that matches
+// the word. Its status is 0 when none does.
+func (s *Shell) runCase(c *parser.CaseClause) {
+	word, err := parser.Expand(c.Word, s)
+	if err != nil {
+		s.expansionError(err)
+		return
+	}
+
+	for _, item := range c.Items {
+		for _, w := range item.Patterns {
+			pattern, err := parser.Pattern(w, s)
+			if err != nil {
+				s.expansionError(err)
+				return
+			}
+			if parser.Match(pattern, word) {
+				s.status = 0
+				s.runList(item.Body)
+				return
+			}
+		}
+	}
+	s.status = 0
+}
+
+// call runs a function with args as $1, $2 and so on. The prefix
+// assignments of the call are local to it, like the variables it
+// declares with local.
+func (s *Shell) call(body parser.Command, args, assigns []string) int {
+	savedArgs, savedLoops := s.args, s.loops
+	s.args, s.loops = args, 0
+	s.frames = append(s.frames, frame{})
+	s.calls++
+	defer func() {
+		s.calls--
+		s.restore(s.frames[len(s.frames)-1])
+		s.frames = s.frames[:len(s.frames)-1]
+		s.args, s.loops = savedArgs, savedLoops
+	}()
+
+	for _, a := range assigns {
+		name, value, _ := strings.Cut(a, "=")
+		s.local(name)
+		if err := s.Set(name, value); err != nil {
+			_ = handler.Report(s.stderr, err)
+			return 1
+		}
+	}
+
+	s.runCompound(body)
+	if s.jump == returnJump {
+		s.jump = noJump
+	}
+	return s.status
+}
+
+// subshell returns a stage function that runs run on a copy of the shell,
+// for a compound command, function call or built-in in a pipeline or in
+// the background. The copy is taken right away, so that it does not race
+// with the shell, and what run changes, the working directory included,
+// stays in it.
+func (s *Shell) subshell(run func(sub *Shell)) func(std handler.Stdio) error {
+	return s.copyShell().stage(run)
+}
+
+func (s *Shell) copyShell() *Shell {
+	sub := &Shell{
+		vars:           make(map[string]*variable, len(s.vars)),
+		status:         s.status,
+		options:        s.options,
+		name:           s.name,
+		args:           s.args,
+		lastBackground: s.lastBackground,
+		funcs:          maps.Clone(s.funcs),
+		calls:          s.calls,
+		loops:          s.loops,
+		tested:         s.tested,
+		procRoot:       s.procRoot,
+		isSubshell:     true,
+		dir:            s.dir,
+	}
+	if sub.dir == "" {
+		// A failed Getwd leaves the copy on the process's directory.
+		sub.dir, _ = os.Getwd()
+	}
+	for name, v := range s.vars {
+		copied := *v
+		sub.vars[name] = &copied
+	}
+	if len(s.frames) > 0 {
+		sub.frames = []frame{{}}
+	}
+	// The jobs and the terminal stay with the shell.
+	sub.options.monitor = false
+	return sub
+}
+
+// stage returns a stage function that runs run on s with the stage's
+// stdin, stdout and stderr.
+func (s *Shell) stage(run func(s *Shell)) func(std handler.Stdio) error {
+	return func(std handler.Stdio) error {
+		s.stdin, s.stdout, s.stderr = std.In, std.Out, std.Err
+		run(s)
+		return statusError(s.status)
+	}
+}
+
+// breakBuiltin leaves the n innermost loops, one by default.
+func (s *Shell) breakBuiltin(args []string, _ io.Writer) error {
+	return s.loopJump("break", breakJump, args)
+}
+
+// continueBuiltin goes on with the next iteration of the nth innermost
+// loop.
+func (s *Shell) continueBuiltin(args []string, _ io.Writer) error {
+	return s.loopJump("continue", continueJump, args)
+}
+
+func (s *Shell) loopJump(name string, kind jump, args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("%s: %s: %w", name, args[0], errLoopCount)
+		}
+	}
+	if s.loops == 0 {
+		return fmt.Errorf("%s: %w", name, errNotInLoop)
+	}
+
+	s.jump, s.jumpCount = kind, min(n, s.loops)
+	return nil
+}
+
+// returnBuiltin ends a function or a sourced file with status n, or with
+// that of the last command.
+func (s *Shell) returnBuiltin(args []string, _ io.Writer) error {
+	if s.calls == 0 {
+		return fmt.Errorf("return: %w", errNotInFunction)
+	}
+
+	status := s.status
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("return: %s: %w", args[0], errNumericArgument)
+		}
+		status = n & 0xff
+	}
+
+	s.jump = returnJump
+	return statusError(status)
+}
@@ -0,0 +1,17 @@
+package shell
+
+import "errors"
+
+var (
+	errInvalidOption = errors.New("invalid option")
+	errInvalidName   = errors.New("not a valid identifier")
+	errNoSuchJob     = errors.New("no such job")
+
+	errNumericArgument  = errors.New("numeric argument required")
+	errShiftCount       = errors.New("shift count out of range")
+	errFilenameRequired = errors.New("filename argument required")
+
+	errNotInLoop     = errors.New("only meaningful in a `for', `while', or `until' loop")
+	errLoopCount     = errors.New("loop count out of range")
+	errNotInFunction = errors.New("can only be used in a function or sourced file")
+)
@@ -0,0 +1,352 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"wb-tech-l2/15/go-shell/internal/handler"
+	"wb-tech-l2/15/go-shell/internal/parser"
+)
+
+// runList runs the items of a list until one of them exits the shell or
+// breaks out of it.
+func (s *Shell) runList(list *parser.List) {
+	for _, item := range list.Items {
+		if s.exited || s.jump != noJump {
+			return
+		}
+		s.runAndOr(item)
+	}
+}
+
+// runAndOr runs the first pipeline, then each following one only if the
+// status so far lets its && or || through. With set -e a failure exits
+// the shell, unless it is tested: by && or ||, by !, or as a condition.
+func (s *Shell) runAndOr(andOr *parser.AndOr) {
+	if andOr.Background {
+		s.runBackground(andOr)
+		return
+	}
+
+	run := func(i int) {
+		p := andOr.Pipelines[i]
+		tested := i < len(andOr.Ops) || p.Negate
+		if tested {
+			s.tested++
+		}
+		s.runPipeline(p)
+		if tested {
+			s.tested--
+		}
+	}
+
+	last := 0
+	run(0)
+
+	for i, op := range andOr.Ops {
+		if s.exited || s.jump != noJump {
+			return
+		}
+		if (op == "&&") == (s.status == 0) {
+			last = i + 1
+			run(last)
+		}
+	}
+
+	if s.options.errexit && s.tested == 0 && s.jump == noJump && s.status != 0 &&
+		last == len(andOr.Ops) && !andOr.Pipelines[last].Negate {
+		s.exited = true
+	}
+}
+
+// runPipeline runs a pipeline and sets $? from it. A lone compound
+// command runs in the shell itself.
+func (s *Shell) runPipeline(p *parser.Pipeline) {
+	if _, simple := p.Commands[0].(*parser.SimpleCommand); !simple && len(p.Commands) == 1 {
+		s.runCompound(p.Commands[0])
+	} else {
+		stages, assigns, err := s.expandPipeline(p)
+		if err != nil {
+			s.expansionError(err)
+			return
+		}
+		s.status = handler.ExitStatus(s.runStages(stages, assigns, p.String()))
+	}
+
+	if p.Negate {
+		s.status = negate(s.status)
+	}
+}
+
+// runBackground starts a pipeline as a job and does not wait for it.
+// Without job control it reads from /dev/null instead of the terminal. An
+// and-or list runs as one job on a copy of the shell, like a subshell.
+func (s *Shell) runBackground(andOr *parser.AndOr) {
+	j := &job{text: andOr.Pipelines[0].String()}
+	var stages []handler.Stage
+	if len(andOr.Pipelines) > 1 {
+		j.list = s.copyShell()
+		list := *andOr
+		list.Background = false
+		j.text = list.String()
+		stages = []handler.Stage{{Run: j.list.stage(func(sub *Shell) { sub.runAndOr(&list) })}}
+	} else {
+		var err error
+		if stages, _, err = s.expandPipeline(andOr.Pipelines[0]); err != nil {
+			s.expansionError(err)
+			return
+		}
+	}
+	if !s.options.monitor || j.list != nil {
+		// A list has no process group of its own for the terminal to stop
+		// when it reads.
+		null := handler.Redirect{Fd: 0, Kind: handler.RedirectInput, Target: os.DevNull}
+		stages[0].Redirects = append([]handler.Redirect{null}, stages[0].Redirects...)
+	}
+
+	started, err := handler.StartPipeline(stages, s.stdio(), s.pipelineOptions(false))
+	if err != nil {
+		_ = handler.Report(s.stderr, err)
+		s.status = 1
+		return
+	}
+
+	j.Job = started
+	s.addJob(j)
+	if n := len(j.Pids); n > 0 {
+		s.lastBackground = j.Pids[n-1]
+	}
+	if s.options.monitor {
+		if j.list != nil {
+			_, _ = fmt.Fprintf(s.stderr, "[%d]\n", j.id)
+		} else {
+			_, _ = fmt.Fprintf(s.stderr, "[%d] %d\n", j.id, s.lastBackground)
+		}
+	}
+	s.status = 0
+}
+
+// expandPipeline expands every command of a pipeline. The assignments
+// are those of a lone command. Compound commands, function calls and the
+// shell's built-ins become stages that run on a copy of the shell, so
+// that cd or exit in a pipeline does not reach the shell itself.
+func (s *Shell) expandPipeline(p *parser.Pipeline) ([]handler.Stage, []string, error) {
+	stages := make([]handler.Stage, len(p.Commands))
+	var assigns []string
+
+	for i, c := range p.Commands {
+		simple, ok := c.(*parser.SimpleCommand)
+		if !ok {
+			stages[i] = handler.Stage{Run: s.subshell(func(sub *Shell) { sub.runCompound(c) })}
+			continue
+		}
+
+		stage, stageAssigns, err := s.expandCommand(simple)
+		if err != nil {
+			return nil, nil, err
+		}
+		if body, ok := s.funcs[stage.Name]; ok {
+			stage.Run = s.subshell(func(sub *Shell) { sub.call(body, stage.Args, stageAssigns) })
+		} else if _, ok := s.builtin(stage.Name); ok {
+			stage.Run = s.subshell(func(sub *Shell) {
+				b, _ := sub.builtin(stage.Name)
+				sub.status = handler.ExitStatus(handler.Report(sub.stderr, b(stage.Args, sub.stdout)))
+			})
+		}
+		stages[i] = stage
+		if len(p.Commands) == 1 {
+			assigns = stageAssigns
+		}
+	}
+	return stages, assigns, nil
+}
+
+func (s *Shell) pipelineOptions(foreground bool) handler.Options {
+	return handler.Options{
+		Pipefail:   s.options.pipefail,
+		Setpgid:    s.options.monitor,
+		Foreground: s.options.monitor && foreground,
+		Tty:        s.tty,
+	}
+}
+
+func negate(status int) int {
+	if status == 0 {
+		return 1
+	}
+	return 0
+}
+
+// runStages runs a pipeline as a foreground job. A lone built-in,
+// function call or assignment runs in the shell itself.
+func (s *Shell) runStages(stages []handler.Stage, assigns []string, text string) error {
+	stage := stages[0]
+	body, isFunc := s.funcs[stage.Name]
+	b, ok := s.builtin(stage.Name)
+	if len(stages) > 1 || (!ok && !isFunc && stage.Name != "") {
+		started, err := handler.StartPipeline(stages, s.stdio(), s.pipelineOptions(true))
+		if err != nil {
+			return handler.Report(s.stderr, err)
+		}
+		return s.waitForeground(&job{Job: started, text: text})
+	}
+
+	std, err := stage.Open(s.stdio())
+	if err != nil {
+		return handler.Report(std.Err, err)
+	}
+	defer func() { _ = std.Close() }()
+
+	if stage.Name == "" {
+		// Assignments without a command set shell variables.
+		for _, a := range assigns {
+			name, value, _ := strings.Cut(a, "=")
+			if err = s.Set(name, value); err != nil {
+				return handler.Report(std.Err, err)
+			}
+		}
+		return nil
+	}
+
+	if isFunc {
+		status := 0
+		s.withStdio(std, func() { status = s.call(body, stage.Args, assigns) })
+		return statusError(status)
+	}
+
+	return handler.Report(std.Err, b(stage.Args, std.Out))
+}
+
+// expansionError reports a failed expansion. It ends a script or a -c
+// string, as POSIX has a non-interactive shell do, but not a prompt.
+func (s *Shell) expansionError(err error) {
+	_, _ = fmt.Fprintf(s.stderr, "%s: %s\n", shellName, err)
+	s.status = 1
+	if !s.interactive {
+		s.exited = true
+	}
+}
+
+func statusError(status int) error {
+	if status != 0 {
+		return &handler.StatusError{Code: status}
+	}
+	return nil
+}
+
+// expandCommand turns a simple command into a pipeline stage. The
+// NAME=value assignments it returns are for the shell when there is no
+// command name, and for a function; programs find them in the stage's
+// environment.
+func (s *Shell) expandCommand(c *parser.SimpleCommand) (handler.Stage, []string, error) {
+	var stage handler.Stage
+
+	var assigns []string
+	for _, a := range c.Assigns {
+		value, err := parser.Expand(a.Value, s)
+		if err != nil {
+			return stage, nil, err
+		}
+		assigns = append(assigns, a.Name+"="+value)
+	}
+
+	var fields []string
+	for _, w := range c.Words {
+		wordFields, err := parser.Fields(w, s)
+		if err != nil {
+			return stage, nil, err
+		}
+		fields = append(fields, wordFields...)
+	}
+
+	for _, r := range c.Redirects {
+		redirects, err := s.redirection(r)
+		if err != nil {
+			return stage, nil, err
+		}
+		stage.Redirects = append(stage.Redirects, redirects...)
+	}
+
+	if s.options.xtrace {
+		s.trace(slices.Concat(assigns, fields))
+	}
+
+	stage.Dir = s.dir
+	if len(fields) == 0 {
+		return stage, assigns, nil
+	}
+	stage.Name = fields[0]
+	if len(fields) > 1 {
+		stage.Args = fields[1:]
+	}
+
+	if len(assigns) > 0 || s.isSubshell {
+		stage.Env = append(s.environ(), assigns...)
+	}
+	return stage, assigns, nil
+}
+
+// trace prints an expanded command for set -x, after the PS4 prompt.
+// Words that would not read back as one word are quoted.
+func (s *Shell) trace(words []string) {
+	prefix, ok := s.Get("PS4")
+	if !ok {
+		prefix = "+ "
+	}
+
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		name, value, isAssign := strings.Cut(w, "=")
+		if isAssign && parser.IsName(name) {
+			quoted[i] = name + "=" + quote(value)
+		} else {
+			quoted[i] = quote(w)
+		}
+	}
+	_, _ = fmt.Fprintln(s.stderr, prefix+strings.Join(quoted, " "))
+}
+
+func quote(w string) string {
+	plain := w != "" && strings.IndexFunc(w, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("-_./:,+@%=", c)
+	}) < 0
+	if plain {
+		return w
+	}
+	return "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+}
+
+func (s *Shell) redirection(r *parser.Redirect) ([]handler.Redirect, error) {
+	if r.Op == "<<" || r.Op == "<<-" {
+		body, err := parser.Expand(r.Body, s)
+		return []handler.Redirect{{Fd: r.Fd, Kind: handler.RedirectHereDoc, Target: body}}, err
+	}
+
+	target, err := parser.Expand(r.Target, s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Op {
+	case "<":
+		return []handler.Redirect{{Fd: r.Fd, Kind: handler.RedirectInput, Target: s.path(target)}}, nil
+	case ">":
+		return []handler.Redirect{{Fd: r.Fd, Kind: handler.RedirectOutput, Target: s.path(target)}}, nil
+	case ">>":
+		return []handler.Redirect{{Fd: r.Fd, Kind: handler.RedirectAppend, Target: s.path(target)}}, nil
+	case "<&", ">&":
+		return []handler.Redirect{{Fd: r.Fd, Kind: handler.RedirectDup, Target: target}}, nil
+	}
+
+	// &> and &>> send both stdout and stderr to the file.
+	kind := handler.RedirectOutput
+	if strings.HasSuffix(r.Op, ">>") {
+		kind = handler.RedirectAppend
+	}
+	return []handler.Redirect{
+		{Fd: 1, Kind: kind, Target: s.path(target)},
+		{Fd: 2, Kind: handler.RedirectDup, Target: "1"},
+	}, nil
+}
@@ -0,0 +1,353 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"wb-tech-l2/15/go-shell/internal/handler"
+)
+
+// job is a pipeline in the jobs table: one started with & or one stopped
+// with Ctrl+Z. An and-or list started with & is a job too, run by list.
+type job struct {
+	*handler.Job
+	id       int
+	text     string
+	reported handler.JobState // last state the user was told about
+	list     *Shell
+}
+
+func (s *Shell) addJob(j *job) {
+	j.id = 1
+	if n := len(s.jobs); n > 0 {
+		j.id = s.jobs[n-1].id + 1
+	}
+	s.jobs = append(s.jobs, j)
+}
+
+func (s *Shell) removeJob(j *job) {
+	for i, other := range s.jobs {
+		if other == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// marker is "+" for the current job, the one fg and bg use by default,
+// and "-" for the previous one.
+func (s *Shell) marker(j *job) string {
+	switch n := len(s.jobs); {
+	case n > 0 && s.jobs[n-1] == j:
+		return "+"
+	case n > 1 && s.jobs[n-2] == j:
+		return "-"
+	}
+	return " "
+}
+
+// formatJob formats a job the way jobs lists it.
+func (s *Shell) formatJob(j *job, state handler.JobState) string {
+	status, text := state.String(), j.text
+	switch {
+	case state == handler.JobRunning:
+		text += " &"
+	case state == handler.JobDone:
+		switch code := handler.ExitStatus(j.Err()); {
+		case code > 128 && code < 128+32:
+			// "Terminated", "Killed" and so on.
+			name := syscall.Signal(code - 128).String()
+			status = strings.ToUpper(name[:1]) + name[1:]
+		case code != 0:
+			status = "Exit " + strconv.Itoa(code)
+		}
+	}
+	return fmt.Sprintf("[%d]%s  %-24s%s\n", j.id, s.marker(j), status, text)
+}
+
+// findJob resolves a job spec: %N, %+ or %% for the current job, %- for
+// the previous one, %text for the job whose command starts with text, or
+// the PID of one of its processes.
+func (s *Shell) findJob(spec string) (*job, error) {
+	n := len(s.jobs)
+	switch {
+	case (spec == "" || spec == "%" || spec == "%%" || spec == "%+") && n > 0:
+		return s.jobs[n-1], nil
+	case spec == "%-" && n > 1:
+		return s.jobs[n-2], nil
+	}
+
+	for _, j := range s.jobs {
+		if id, ok := strings.CutPrefix(spec, "%"); ok {
+			if strconv.Itoa(j.id) == id || (id != "" && !isNumber(id) && strings.HasPrefix(j.text, id)) {
+				return j, nil
+			}
+			continue
+		}
+		for _, pid := range j.Pids {
+			if strconv.Itoa(pid) == spec {
+				return j, nil
+			}
+		}
+	}
+
+	if spec == "" {
+		spec = "current"
+	}
+	return nil, fmt.Errorf("%s: %w", spec, errNoSuchJob)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// waitForeground waits for a foreground job and then takes the terminal
+// back. A job stopped with Ctrl+Z goes to the jobs table. Either that or
+// a job killed with Ctrl+C interrupts the command line, and the next
+// prompt starts on a fresh line.
+func (s *Shell) waitForeground(j *job) error {
+	s.foreground.Store(j)
+	state := j.Wait()
+	s.foreground.Store(nil)
+	if s.options.monitor {
+		_ = setForeground(s.tty, s.pgid)
+	}
+
+	if state == handler.JobStopped {
+		if j.id == 0 {
+			s.addJob(j)
+		}
+		j.reported = state
+		_, _ = fmt.Fprint(s.stderr, "\n"+s.formatJob(j, state))
+		s.interrupt()
+		return &handler.StatusError{Code: 128 + int(syscall.SIGTSTP)}
+	}
+
+	s.removeJob(j)
+	err := j.Err()
+	if handler.ExitStatus(err) == 128+int(syscall.SIGINT) {
+		s.interrupt()
+	}
+	if s.options.monitor {
+		switch handler.ExitStatus(err) - 128 {
+		case int(syscall.SIGINT):
+			_, _ = fmt.Fprintln(s.stderr)
+		case int(syscall.SIGQUIT):
+			_, _ = fmt.Fprintln(s.stderr, "Quit")
+		}
+	}
+	return err
+}
+
+// interrupt ends the command line running at a prompt, loops and all, the
+// way bash does after Ctrl+C or Ctrl+Z. Scripts and subshells go on.
+func (s *Shell) interrupt() {
+	if s.interactive {
+		s.jump = interruptJump
+	}
+}
+
+// waitJob waits for a job like Job.Wait. At a prompt Ctrl+C reaches the
+// shell itself while it waits, and ends the wait early with false.
+func (s *Shell) waitJob(j *job) (handler.JobState, bool) {
+	if !s.interactive {
+		return j.Wait(), true
+	}
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, syscall.SIGINT)
+	defer signal.Stop(interrupts)
+
+	states := make(chan handler.JobState, 1)
+	go func() { states <- j.Wait() }()
+	select {
+	case state := <-states:
+		return state, true
+	case <-interrupts:
+		return 0, false
+	}
+}
+
+// jobProcesses resolves %job for kill: the job's process group, or its
+// processes when it has none. For an and-or list that is the pipeline it
+// is running. kill names the spec in its errors itself.
+func (s *Shell) jobProcesses(spec string) ([]int, error) {
+	j, err := s.findJob(spec)
+	if err != nil {
+		return nil, errNoSuchJob
+	}
+	if j.list != nil {
+		running := j.list.foreground.Load()
+		if running == nil {
+			return nil, errNoSuchJob
+		}
+		j = running
+	}
+	if j.Pgid != 0 {
+		return []int{-j.Pgid}, nil
+	}
+	if len(j.Pids) == 0 {
+		return nil, errNoSuchJob
+	}
+	return j.Pids, nil
+}
+
+// kill is the handler's kill that also knows the jobs. A stopped job is
+// continued after the signal, or it could not act on it.
+func (s *Shell) kill(args []string, w io.Writer) error {
+	err := handler.Kill(args, w, s.jobProcesses)
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "%") {
+			continue
+		}
+		if j, findErr := s.findJob(arg); findErr == nil && j.State() == handler.JobStopped {
+			_ = j.Continue()
+		}
+	}
+	return err
+}
+
+// notifyJobs tells about background jobs that have finished or stopped
+// since the last prompt. Finished jobs leave the table.
+func (s *Shell) notifyJobs(w io.Writer) {
+	for _, j := range append([]*job(nil), s.jobs...) {
+		state := j.State()
+		if state == j.reported {
+			continue
+		}
+		j.reported = state
+
+		if state != handler.JobRunning {
+			_, _ = fmt.Fprint(w, s.formatJob(j, state))
+		}
+		if state == handler.JobDone {
+			s.removeJob(j)
+		}
+	}
+}
+
+// jobsBuiltin lists the jobs. Finished ones are listed a last time and
+// removed.
+func (s *Shell) jobsBuiltin(args []string, w io.Writer) error {
+	list := s.jobs
+	if len(args) > 0 {
+		list = nil
+		for _, spec := range args {
+			j, err := s.findJob(spec)
+			if err != nil {
+				return fmt.Errorf("jobs: %w", err)
+			}
+			list = append(list, j)
+		}
+	}
+
+	var done []*job
+	for _, j := range list {
+		state := j.State()
+		j.reported = state
+		if _, err := io.WriteString(w, s.formatJob(j, state)); err != nil {
+			return err
+		}
+		if state == handler.JobDone {
+			done = append(done, j)
+		}
+	}
+
+	for _, j := range done {
+		s.removeJob(j)
+	}
+	return nil
+}
+
+// fg continues a job in the foreground and waits for it.
+func (s *Shell) fg(args []string, w io.Writer) error {
+	j, err := s.findJob(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("fg: %w", err)
+	}
+
+	if _, err = fmt.Fprintln(w, j.text); err != nil {
+		return err
+	}
+	if s.options.monitor && j.Pgid != 0 {
+		if err = setForeground(s.tty, j.Pgid); err != nil {
+			return fmt.Errorf("fg: %w", err)
+		}
+	}
+	if err = j.Continue(); err != nil {
+		return fmt.Errorf("fg: %w", err)
+	}
+	j.reported = handler.JobRunning
+
+	return s.waitForeground(j)
+}
+
+// bg continues stopped jobs in the background.
+func (s *Shell) bg(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+
+	for _, spec := range args {
+		j, err := s.findJob(spec)
+		if err != nil {
+			return fmt.Errorf("bg: %w", err)
+		}
+		if err = j.Continue(); err != nil {
+			return fmt.Errorf("bg: %w", err)
+		}
+		j.reported = handler.JobRunning
+
+		if _, err = fmt.Fprintf(w, "[%d]%s %s &\n", j.id, s.marker(j), j.text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait waits for the given jobs, or for all of them, to finish. Its
+// status is that of the last job waited for; a stopped job ends the wait.
+// At a prompt Ctrl+C ends it with status 130.
+func (s *Shell) wait(args []string, _ io.Writer) error {
+	list := append([]*job(nil), s.jobs...)
+	if len(args) > 0 {
+		list = nil
+		for _, spec := range args {
+			j, err := s.findJob(spec)
+			if err != nil {
+				return fmt.Errorf("wait: %w", err)
+			}
+			list = append(list, j)
+		}
+	}
+
+	var err error
+	for _, j := range list {
+		state, ok := s.waitJob(j)
+		if !ok {
+			if s.options.monitor {
+				_, _ = fmt.Fprintln(s.stderr)
+			}
+			s.interrupt()
+			return &handler.StatusError{Code: 128 + int(syscall.SIGINT)}
+		}
+		if state == handler.JobStopped {
+			err = &handler.StatusError{Code: 128 + int(syscall.SIGTSTP)}
+			continue
+		}
+
+		s.removeJob(j)
+		err = j.Err()
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultPrompt             = `\u@\h:\w\$ `
+	defaultContinuationPrompt = "> "
+)
+
+// prompt expands PS1. It understands the bash escapes \u, \h, \H, \w, \W,
+// \$, \n and \\.
+func (s *Shell) prompt() string {
+	ps1, ok := s.Get("PS1")
+	if !ok {
+		ps1 = defaultPrompt
+	}
+	return expandPrompt(ps1)
+}
+
+// continuationPrompt expands PS2, shown while a command is incomplete.
+func (s *Shell) continuationPrompt() string {
+	ps2, ok := s.Get("PS2")
+	if !ok {
+		ps2 = defaultContinuationPrompt
+	}
+	return expandPrompt(ps2)
+}
+
+func expandPrompt(ps1 string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(ps1); i++ {
+		if ps1[i] != '\\' || i+1 == len(ps1) {
+			b.WriteByte(ps1[i])
+			continue
+		}
+
+		i++
+		switch ps1[i] {
+		case 'u':
+			if u, err := user.Current(); err == nil {
+				b.WriteString(u.Username)
+			}
+		case 'h', 'H':
+			host, _ := os.Hostname()
+			if ps1[i] == 'h' {
+				host, _, _ = strings.Cut(host, ".")
+			}
+			b.WriteString(host)
+		case 'w', 'W':
+			dir, _ := os.Getwd()
+			if home, err := os.UserHomeDir(); err == nil && (dir == home || strings.HasPrefix(dir, home+"/")) {
+				dir = "~" + dir[len(home):]
+			}
+			if ps1[i] == 'W' && dir != "/" && dir != "~" {
+				dir = filepath.Base(dir)
+			}
+			b.WriteString(dir)
+		case '$':
+			if os.Geteuid() == 0 {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('$')
+			}
+		case 'n':
+			b.WriteByte('\n')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(ps1[i])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"wb-tech-l2/15/go-shell/internal/handler"
+)
+
+// SetArgs sets $0 and the positional parameters, as for a script run with
+// arguments.
+func (s *Shell) SetArgs(name string, args []string) {
+	s.name = name
+	s.args = args
+}
+
+// RunFile runs a script, the way "go-shell script.sh" does, and returns
+// its exit status. A #! line at the top is a comment to the shell.
+func (s *Shell) RunFile(path string) (int, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return s.Run(string(text)), nil
+}
+
+// source runs a file in the current shell, so that its variables and cd
+// stay. Arguments after the file are the positional parameters meanwhile.
+func (s *Shell) source(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("source: %w", errFilenameRequired)
+	}
+
+	if len(args) > 1 {
+		saved := s.args
+		s.args = args[1:]
+		defer func() { s.args = saved }()
+	}
+	stdout := s.stdout
+	s.stdout = w
+	s.calls++
+	defer func() {
+		s.stdout = stdout
+		s.calls--
+	}()
+
+	status, err := s.RunFile(s.path(args[0]))
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	if s.jump == returnJump {
+		s.jump = noJump
+	}
+	return statusError(status)
+}
+
+// exit ends the shell with status n, or with that of the last command.
+func (s *Shell) exit(args []string, _ io.Writer) error {
+	s.exited = true
+
+	status := s.status
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintf(s.stderr, "exit: %s: %s\n", args[0], errNumericArgument)
+			return &handler.StatusError{Code: 2}
+		}
+		status = n & 0xff
+	}
+
+	return statusError(status)
+}
+
+// shift drops the first n positional parameters, one by default.
+func (s *Shell) shift(args []string, _ io.Writer) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("shift: %s: %w", args[0], errNumericArgument)
+		}
+	}
+	if n < 0 || n > len(s.args) {
+		return fmt.Errorf("shift: %d: %w", n, errShiftCount)
+	}
+
+	s.args = s.args[n:]
+	return nil
+}
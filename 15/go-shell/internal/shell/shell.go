@@ -0,0 +1,157 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"wb-tech-l2/15/go-shell/internal/handler"
+	"wb-tech-l2/15/go-shell/internal/lineedit"
+	"wb-tech-l2/15/go-shell/internal/parser"
+	"wb-tech-l2/15/go-shell/internal/procfs"
+
+	"golang.org/x/term"
+)
+
+const (
+	shellName    = "go-shell"
+	historyFile  = ".go_shell_history"
+	historyLimit = 1000
+)
+
+// Shell runs command lines one after another. The working directory lives
+// in the process, so cd keeps its effect between lines; only a subshell
+// keeps its own.
+type Shell struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	vars    map[string]*variable
+	status  int
+	options options
+	name    string   // $0, the script or the shell
+	args    []string // $1, $2 and so on
+	exited  bool     // set by exit, and by set -e on a failure
+
+	interactive bool   // reading commands from the prompt
+	procRoot    string // where ps reads processes from
+	isSubshell  bool   // running on a copy of the shell
+	dir         string // working directory of a subshell, "" for the process's
+
+	funcs     map[string]parser.Command
+	frames    []frame // local variables of the running functions
+	calls     int     // running functions and sourced files, for return
+	loops     int     // loops around the running command, for break
+	jump      jump    // pending break, continue, return or interrupt
+	jumpCount int     // loops that a pending break or continue leaves
+	tested    int     // conditions being run, where set -e does not apply
+
+	jobs           []*job
+	foreground     atomic.Pointer[job] // read by the signal forwarder
+	lastBackground int                 // PID for $!
+	tty            int                 // terminal descriptor, with job control
+	pgid           int                 // the shell's own process group
+}
+
+func New() *Shell {
+	return &Shell{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		vars:   environVars(),
+
+		procRoot: procfs.DefaultRoot,
+	}
+}
+
+// SetProcRoot makes ps read processes from the proc filesystem at root
+// instead of /proc.
+func (s *Shell) SetProcRoot(root string) {
+	s.procRoot = root
+}
+
+// Status is the exit status of the last command.
+func (s *Shell) Status() int {
+	return s.status
+}
+
+// Run parses text and executes it, returning the exit status of the last
+// command. Nothing runs when text has a syntax error.
+func (s *Shell) Run(text string) int {
+	list, err := parser.Parse(text)
+	if err != nil {
+		_, _ = fmt.Fprintf(s.stderr, "%s: %s\n", shellName, err)
+		s.status = 2
+		return s.status
+	}
+
+	s.runList(list)
+	return s.status
+}
+
+func (s *Shell) stdio() handler.Stdio {
+	return handler.Stdio{In: s.stdin, Out: s.stdout, Err: s.stderr}
+}
+
+// RunInteractive reads and runs lines until Ctrl+D. Ctrl+C drops the line
+// being typed.
+func (s *Shell) RunInteractive() error {
+	history, err := lineedit.OpenHistory(historyPath(), historyLimit)
+	if err != nil {
+		_, _ = fmt.Fprintf(s.stderr, "history: %s\n", err)
+	}
+	in, ok := s.stdin.(*os.File)
+	if !ok {
+		in = os.Stdin
+	}
+	editor := lineedit.NewTerminal(in, s.stdout, history)
+	s.interactive = true
+
+	if fd := int(in.Fd()); term.IsTerminal(fd) {
+		s.options.monitor = true
+		s.tty, s.pgid = fd, syscall.Getpgrp()
+		defer s.forwardSignals()()
+	}
+
+	for {
+		s.notifyJobs(s.stderr)
+		text, err := s.readCommand(editor)
+		switch {
+		case errors.Is(err, lineedit.ErrInterrupted):
+			s.status = 130
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		s.Run(text)
+		s.jump = noJump
+		if s.exited {
+			return nil
+		}
+	}
+}
+
+// readCommand reads a line, and continuation lines with the PS2 prompt
+// for as long as the command is incomplete: inside quotes, after a pipe
+// or while a here-document is open.
+func (s *Shell) readCommand(editor *lineedit.Editor) (string, error) {
+	return editor.ReadCommand(s.prompt(), s.continuationPrompt(), func(text string) bool {
+		_, err := parser.Parse(text)
+		return !errors.Is(err, parser.ErrIncomplete)
+	})
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFile)
+}
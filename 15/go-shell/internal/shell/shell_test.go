@@ -0,0 +1,717 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"wb-tech-l2/15/go-shell/internal/handler"
+	"wb-tech-l2/15/go-shell/internal/parser"
+
+	"golang.org/x/sys/unix"
+)
+
+func newTestShell(t *testing.T) (*Shell, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = stdin.Close() })
+
+	var stdout, stderr bytes.Buffer
+	sh := &Shell{
+		stdin:  stdin,
+		stdout: &lockedWriter{w: &stdout},
+		stderr: &lockedWriter{w: &stderr},
+		vars:   environVars(),
+	}
+	return sh, &stdout, &stderr
+}
+
+// lockedWriter lets background jobs and the shell write to one buffer.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func TestStages(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []handler.Stage
+	}{
+		{
+			line:     "ls -l",
+			expected: []handler.Stage{{Name: "ls", Args: []string{"-l"}}},
+		},
+		{
+			line: "sort<in>out",
+			expected: []handler.Stage{{Name: "sort", Redirects: []handler.Redirect{
+				{Fd: 0, Kind: handler.RedirectInput, Target: "in"},
+				{Fd: 1, Kind: handler.RedirectOutput, Target: "out"},
+			}}},
+		},
+		{
+			line: "cmd 2>>log 2>&1 | wc -l",
+			expected: []handler.Stage{
+				{Name: "cmd", Redirects: []handler.Redirect{
+					{Fd: 2, Kind: handler.RedirectAppend, Target: "log"},
+					{Fd: 2, Kind: handler.RedirectDup, Target: "1"},
+				}},
+				{Name: "wc", Args: []string{"-l"}},
+			},
+		},
+		{
+			line: "make &> build.log",
+			expected: []handler.Stage{{Name: "make", Redirects: []handler.Redirect{
+				{Fd: 1, Kind: handler.RedirectOutput, Target: "build.log"},
+				{Fd: 2, Kind: handler.RedirectDup, Target: "1"},
+			}}},
+		},
+		{
+			line:     "echo a2>b",
+			expected: []handler.Stage{{Name: "echo", Args: []string{"a2"}, Redirects: []handler.Redirect{{Fd: 1, Kind: handler.RedirectOutput, Target: "b"}}}},
+		},
+		{
+			line:     "> out",
+			expected: []handler.Stage{{Redirects: []handler.Redirect{{Fd: 1, Kind: handler.RedirectOutput, Target: "out"}}}},
+		},
+		{
+			line:     `grep "$W x" 'y z'>$W.txt`,
+			expected: []handler.Stage{{Name: "grep", Args: []string{"w x", "y z"}, Redirects: []handler.Redirect{{Fd: 1, Kind: handler.RedirectOutput, Target: "w.txt"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			sh, _, _ := newTestShell(t)
+			sh.vars["W"] = &variable{value: "w"}
+
+			list, err := parser.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var stages []handler.Stage
+			for _, c := range list.Items[0].Pipelines[0].Commands {
+				stage, _, err := sh.expandCommand(c.(*parser.SimpleCommand))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				stages = append(stages, stage)
+			}
+			if !reflect.DeepEqual(stages, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, stages)
+			}
+		})
+	}
+}
+
+func TestLists(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"sequence", "echo a; echo b\necho c", 0, "a\nb\nc\n"},
+		{"and", "true && echo yes; false && echo no", 1, "yes\n"},
+		{"or", "false || echo yes; true || echo no", 0, "yes\n"},
+		{"chain", "false && echo a || echo b && echo c", 0, "b\nc\n"},
+		{"negation", "! false && ! true", 1, ""},
+		{"status", "false; echo $?; sh -c 'exit 3'; echo $?", 0, "1\n3\n"},
+		{"variables", "A='x  y'; B=$A; echo \"$B\" ${C:-none} ${#A}", 0, "x  y none 4\n"},
+		{"prefix assignment", "V=1 sh -c 'echo $V'; echo \"[$V]\"", 0, "1\n[]\n"},
+		{"export", "export E=1; sh -c 'echo $E'; unset E; sh -c 'echo \"[$E]\"'", 0, "1\n[]\n"},
+		{"unexported", "L=1; sh -c 'echo \"[$L]\"'", 0, "[]\n"},
+		{"here-document expansion", "X=1\ncat <<EOF\n$X \\$X\nEOF\ncat <<'EOF'\n$X\nEOF", 0, "1 $X\n$X\n"},
+		{"unset parameter error", "echo ${NOPE?}; echo $?", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+		})
+	}
+
+	sh, stdout, stderr := newTestShell(t)
+	for _, text := range []string{"| ls", "ls | | wc", "echo >", "echo > | wc", "echo a; )"} {
+		if status := sh.Run(text); status != 2 {
+			t.Errorf("Expected status 2 for %q, got %d", text, status)
+		}
+	}
+	if stdout.Len() > 0 || !bytes.Contains(stderr.Bytes(), []byte("syntax error")) {
+		t.Errorf("Expected only syntax errors, got %q and %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestScripts(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.sh")
+	if err := os.WriteFile(lib, []byte("#!/bin/go-shell\nLIB=loaded\necho \"sourced $# $1\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		text           string
+		expectedStatus int
+		expectedOutput string
+		expectedErr    string
+	}{
+		{"positional", `echo $0 $# "$1" "$@"; printf '[%s]' "$@"; echo`, 0, "script 2 a b a b c\n[a b][c]\n", ""},
+		{"set --", `set -- x "y z"; echo $# $2; set --; echo $#`, 0, "2 y z\n0\n", ""},
+		{"shift", `shift; echo $# $1; shift 2`, 1, "1 c\n", "shift count out of range"},
+		{"exit", "echo a; exit 3; echo b", 3, "a\n", ""},
+		{"exit with the last status", "false; exit", 1, "", ""},
+		{"exit in a list", "true && exit 4 || echo no\necho b", 4, "", ""},
+		{"bad exit status", "exit x; echo b", 2, "", "numeric argument required"},
+		{"errexit", "set -e; echo a; false; echo b", 1, "a\n", ""},
+		{"errexit exceptions", "set -e; false || true; false && true; ! true; echo a; true && false; echo b", 1, "a\n", ""},
+		{"nounset", "set -u; echo ${X-d}; echo $X; echo after", 1, "d\n", "go-shell: X: unbound variable\n"},
+		{"nounset in a loop", "set -u; for x in a; do echo $X; done; echo after", 1, "", "unbound variable"},
+		{"nounset and errexit", "set -eu; echo $X; echo b", 1, "", "unbound variable"},
+		{"xtrace", "set -x; V='a b' echo \"$1\" x; set +x; echo y", 0, "a b x\ny\n", "+ V='a b' echo 'a b' x\n+ set +x\n"},
+		{"flags", "set -eux; echo $-", 0, "eux\n", ""},
+		{"invalid option", "set -q", 1, "", "set: -q: invalid option"},
+		{"source", "source " + lib + "; echo $LIB; . " + lib + " z; echo $1", 0, "sourced 2 a b\nloaded\nsourced 1 z\na b\n", ""},
+		{"source missing file", "source " + filepath.Join(dir, "missing"), 1, "", "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+			sh.SetArgs("script", []string{"a b", "c"})
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+			if !bytes.Contains(stderr.Bytes(), []byte(tt.expectedErr)) {
+				t.Errorf("Expected stderr with %q, got %q", tt.expectedErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestControlFlow(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out")
+	for name, content := range map[string]string{"a.go": "package a\n", "b.go": ""} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		text           string
+		expectedStatus int
+		expectedOutput string
+		expectedErr    string
+	}{
+		{"if", "if false; then echo a; elif true; then echo b; else echo c; fi", 0, "b\n", ""},
+		{"if else", "if [ a = b ]; then echo a; else echo c; fi", 0, "c\n", ""},
+		{"if without a branch", "false; if false; then echo a; fi; echo $?", 0, "0\n", ""},
+		{"while", "i=0; while [ $i != 3 ]; do echo $i; i=${i}1; [ $i = 011 ] && i=3; done", 0, "0\n01\n", ""},
+		{"until", "until true; do echo no; done; echo $?", 0, "0\n", ""},
+		{"for", "for x in a 'b c' \"$@\"; do echo \"[$x]\"; done", 0, "[a]\n[b c]\n[a b]\n[c]\n", ""},
+		{"for over files", "for f in " + dir + "/*.go; do [ -s \"$f\" ] || echo \"$f is empty\"; done", 0, filepath.Join(dir, "b.go") + " is empty\n", ""},
+		{"for over no files", "for f in " + dir + "/*.md; do echo \"$f\"; done", 0, filepath.Join(dir, "*.md") + "\n", ""},
+		{"for positional", "for x; do echo $x; done", 0, "a b\nc\n", ""},
+		{"break and continue", "for x in 1 2 3 4; do [ $x = 2 ] && continue; [ $x = 4 ] && break; echo $x; done", 0, "1\n3\n", ""},
+		{"break n", "for x in 1 2; do for y in a b; do echo $x$y; break 2; done; done; echo end", 0, "1a\nend\n", ""},
+		{"continue n", "for x in 1 2; do for y in a b; do echo $x$y; continue 2; done; done", 0, "1a\n2a\n", ""},
+		{"break outside a loop", "break; echo $?", 0, "1\n", "only meaningful in a `for', `while', or `until' loop"},
+		{"case", "for f in a.go b.txt C; do case $f in *.go) echo go $f;; *.txt|*.md) echo text;; [A-Z]) echo upper;; esac; done", 0, "go a.go\ntext\nupper\n", ""},
+		{"case quoted pattern", "x='*'; case a in \"$x\") echo star;; *) echo other;; esac", 0, "other\n", ""},
+		{"function", "greet() { echo \"hello $1\"; return 3; echo no; }; greet world; echo $?", 0, "hello world\n3\n", ""},
+		{"function arguments", "f() { echo $# $1; }; f x y; echo $1", 0, "2 x\na b\n", ""},
+		{"local", "x=1; f() { local x=2 y; y=3; echo $x $y; }; f; echo $x ${y-unset}", 0, "2 3\n1 unset\n", ""},
+		{"local outside a function", "local x", 1, "", "can only be used in a function"},
+		{"prefix assignment to a function", "f() { echo $V; }; V=1 f; echo ${V-unset}", 0, "1\nunset\n", ""},
+		{"recursion", "count() { if [ $1 != xxx ]; then echo $1; count ${1}x; fi; }; count x", 0, "x\nxx\n", ""},
+		{"return outside a function", "return", 1, "", "can only be used in a function"},
+		{"function in a pipeline", "f() { echo a; echo b; }; f | tr a-z A-Z", 0, "A\nB\n", ""},
+		{"compound in a pipeline", "for x in a b; do echo $x; done | wc -l | tr -d ' '", 0, "2\n", ""},
+		{"pipeline stages are subshells", "x=1; { x=2; echo $x; } | cat; echo $x", 0, "2\n1\n", ""},
+		{"redirected compound", "for x in a b; do echo $x; done > " + file + "; cat " + file, 0, "a\nb\n", ""},
+		{"brace group", "{ echo a; echo b >&2; } 2>&1", 0, "a\nb\n", ""},
+		{"errexit in conditions", "set -e; if false; then echo no; fi; while false; do :; done; f() { false; }; f || true; echo a; f; echo b", 1, "a\n", ""},
+		{"test status", "test 1 -lt 2 && [ -n x ] && ! [ -z x ]; echo $?; [ a; echo $?", 0, "0\n2\n", "missing `]'"},
+		{"exit in a loop", "for x in 1 2; do exit 5; done; echo no", 5, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+			sh.SetArgs("script", []string{"a b", "c"})
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+			if !bytes.Contains(stderr.Bytes(), []byte(tt.expectedErr)) {
+				t.Errorf("Expected stderr with %q, got %q", tt.expectedErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestInteractiveExpansionError(t *testing.T) {
+	sh, stdout, stderr := newTestShell(t)
+	sh.interactive = true
+
+	if status := sh.Run("set -u; echo $X; echo $?"); status != 0 {
+		t.Errorf("Expected status 0, got %d", status)
+	}
+	if sh.Run("echo after") != 0 || stdout.String() != "1\nafter\n" {
+		t.Errorf("Expected the shell to go on after the error, got %q", stdout.String())
+	}
+	if stderr.String() != "go-shell: X: unbound variable\n" {
+		t.Errorf("Expected the error on stderr, got %q", stderr.String())
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		interactive    bool
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"for loop at a prompt", "for i in 1 2 3; do echo $i; sh -c 'kill -INT $$'; done; echo after", true, 130, "1\n"},
+		{"while loop at a prompt", "while true; do sh -c 'kill -INT $$'; done; echo after", true, 130, ""},
+		{"condition at a prompt", "while sh -c 'kill -INT $$'; do echo body; done; echo after", true, 130, ""},
+		{"function at a prompt", "f() { sh -c 'kill -INT $$'; echo f; }; f || echo failed", true, 130, ""},
+		{"loop in a script", "for i in 1 2; do echo $i; sh -c 'kill -INT $$'; done; echo after", false, 0, "1\n2\nafter\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+			sh.interactive = tt.interactive
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+
+			// The prompt clears the interrupt before the next line.
+			sh.jump = noJump
+			stdout.Reset()
+			if sh.Run("echo next"); stdout.String() != "next\n" {
+				t.Errorf("Expected the next line to run, got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestStoppedJobInterrupts(t *testing.T) {
+	sh, stdout, stderr := newTestShell(t)
+	sh.interactive = true
+
+	if status := sh.Run("for i in 1 2; do echo $i; sh -c 'kill -STOP $$'; done; echo after"); status != 148 {
+		t.Errorf("Expected status 148, got %d", status)
+	}
+	sh.jump = noJump
+	defer sh.Run("kill -9 %1")
+
+	if stdout.String() != "1\n" {
+		t.Errorf("Expected the loop to stop with the job, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "Stopped") || len(sh.jobs) != 1 {
+		t.Errorf("Expected the stopped job in the table, got %q", stderr.String())
+	}
+}
+
+func TestInterruptWait(t *testing.T) {
+	sh, stdout, _ := newTestShell(t)
+	sh.interactive = true
+	sh.Run("sleep 5 &")
+	defer sh.Run("kill %1")
+
+	stop := sh.forwardSignals()
+	defer stop()
+
+	done := make(chan int, 1)
+	go func() { done <- sh.Run("wait; echo after") }()
+	for {
+		select {
+		case status := <-done:
+			if status != 130 || stdout.String() != "" {
+				t.Errorf("Expected wait to end the line with status 130, got %d and %q", status, stdout.String())
+			}
+			sh.jump = noJump
+			return
+		case <-time.After(50 * time.Millisecond):
+			// The shell catches SIGINT, so the test process survives.
+			if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestRedirections(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "out")
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name           string
+		text           string
+		expectedStatus int
+		expectedOutput string
+		expectedErr    string
+	}{
+		{"truncate and append", "echo one > " + file + "\necho two >> " + file + "\ncat " + file, 0, "one\ntwo\n", ""},
+		{"input", "echo data > " + file + "\ntr a-z A-Z < " + file, 0, "DATA\n", ""},
+		{"stderr to file", "ls " + missing + " 2> " + file + "\nwc -l < " + file, 0, "1\n", ""},
+		{"stderr into the pipe", "ls " + missing + " 2>&1 | wc -l", 0, "1\n", ""},
+		{"both to file", "ls " + missing + " " + file + " &> " + file + "\nwc -l < " + file, 0, "2\n", ""},
+		{"builtin output", "pwd > " + file + "\ncat " + file, 0, mustGetwd(t) + "\n", ""},
+		{"shell builtin output", "set > " + file + "\ncat " + file, 0, "errexit\toff\nnounset\toff\npipefail\toff\nxtrace\toff\n", ""},
+		{"here-document", "cat <<EOF | tr a-z A-Z\nhello\n  world\nEOF\necho after", 0, "HELLO\n  WORLD\nafter\n", ""},
+		{"here-document stripping tabs", "cat <<-END\n\tindented\n\tEND", 0, "indented\n", ""},
+		{"missing input file", "cat < " + missing, 1, "", "no such file"},
+		{"bad descriptor", "echo x 5> " + file, 1, "", "bad file descriptor"},
+		{"unterminated here-document", "cat <<EOF\nbody", 2, "", "here-document"},
+		{"expanded target", "F=" + file + "\necho x > $F\ncat \"$F\"", 0, "x\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+			if !bytes.Contains(stderr.Bytes(), []byte(tt.expectedErr)) {
+				t.Errorf("Expected stderr with %q, got %q", tt.expectedErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestJobs(t *testing.T) {
+	tests := []struct {
+		name           string
+		text           string
+		expectedStatus int
+		expectedOutput string
+		expectedErr    string
+	}{
+		{"wait for all", "sleep 0.1 & echo started; wait; echo $?", 0, "started\n0\n", ""},
+		{"wait for one", "sh -c 'exit 3' & wait $!; echo $?", 0, "3\n", ""},
+		{"builtin", "echo bg & wait %1", 0, "bg\n", ""},
+		{"last pid", "sleep 5 & sh -c \"kill -0 $!\" && echo alive; sh -c \"kill $!\"; wait", 0, "alive\n", ""},
+		{"fg", "sleep 0.1 & fg %sleep", 0, "sleep 0.1\n", ""},
+		{
+			"stop and continue",
+			"sleep 5 & sh -c \"kill -STOP $!\"; wait %1; echo $?; jobs; bg; sh -c \"kill $!\"; wait %1",
+			143,
+			"148\n[1]+  Stopped                 sleep 5\n[1]+ sleep 5 &\n",
+			"",
+		},
+		{"kill a job", "sleep 5 & kill %1; wait %1", 143, "", ""},
+		{"kill a stopped job", "sleep 5 & kill -STOP %sleep; wait; kill -9 %1; wait %1", 137, "", ""},
+		{"no job", "fg", 1, "", "current: no such job"},
+		{"unknown job", "wait %3", 1, "", "%3: no such job"},
+		{"kill an unknown job", "kill %3", 1, "", "kill: %3: no such job\n"},
+		{"and-or list", "true && echo yes & wait %1; echo $?", 0, "yes\n0\n", ""},
+		{"failing and-or list", "false && echo yes || sh -c 'exit 4' & wait %1", 4, "", ""},
+		{"and-or list in the table", "true && sleep 0.2 & jobs; wait", 0, "[1]+  Running                 true && sleep 0.2 &\n", ""},
+		{"kill an and-or list", "sleep 5 && echo done & until kill %1 2>/dev/null; do sleep 0.05; done; wait %1", 143, "", ""},
+		{"and-or list on a copy", "X=1; X=2 && cd / & wait; echo $X; pwd", 0, "1\n" + mustGetwd(t) + "\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+			if !bytes.Contains(stderr.Bytes(), []byte(tt.expectedErr)) {
+				t.Errorf("Expected stderr with %q, got %q", tt.expectedErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestSubshellState(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	tests := []struct {
+		name           string
+		text           string
+		expectedStatus int
+		expectedOutput string
+		expectedErr    string
+	}{
+		{"cd in a pipeline", "cd / | cat; pwd", 0, dir + "\n", ""},
+		{"cd in the background", "cd / & wait; pwd", 0, dir + "\n", ""},
+		{"cd in a compound command", "{ cd sub; pwd; ls -d ../sub; echo x > f; } | cat; cat sub/f; pwd", 0, dir + "/sub\n../sub\nx\n" + dir + "\n", ""},
+		{"failed cd in a pipeline", "cd missing | cat; pwd", 0, dir + "\n", "no such file"},
+		{"exit in a pipeline", "exit 3 | cat; echo after", 0, "after\n", ""},
+		{"export in a pipeline", "export X=1 | cat; echo ${X-unset}", 0, "unset\n", ""},
+		{"export in a compound command", "{ export Y=2; sh -c 'echo $Y'; } | cat; sh -c 'echo ${Y-unset}'", 0, "2\nunset\n", ""},
+		{"lone cd", "cd sub; pwd", 0, dir + "/sub\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(dir)
+			sh, stdout, stderr := newTestShell(t)
+
+			if status := sh.Run(tt.text); status != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (stderr %q)", tt.expectedStatus, status, stderr.String())
+			}
+			if stdout.String() != tt.expectedOutput {
+				t.Errorf("Expected output %q, got %q", tt.expectedOutput, stdout.String())
+			}
+			if !bytes.Contains(stderr.Bytes(), []byte(tt.expectedErr)) {
+				t.Errorf("Expected stderr with %q, got %q", tt.expectedErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestPsProcRoot(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"ps -o pid,comm --sort=-pid", "PID COMMAND\n201 my (odd) prog\n200 sleep\n100 bash\n  2 kthreadd\n  1 systemd\n"},
+		{"ps -o pid= | head -2", "  1\n  2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			sh, stdout, stderr := newTestShell(t)
+			sh.SetProcRoot("../procfs/testdata/proc")
+
+			if status := sh.Run(tt.text); status != 0 {
+				t.Errorf("Expected status 0, got %d (stderr %q)", status, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("Expected output %q, got %q", tt.expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestNotifyJobs(t *testing.T) {
+	sh, _, _ := newTestShell(t)
+	sh.Run("sh -c 'exit 2' &")
+	sh.Run("sleep 5 &")
+	sh.Run("sleep 5 &")
+	defer sh.Run("kill %3")
+
+	sh.Run("kill -9 %2")
+	sh.jobs[0].Wait()
+	sh.jobs[1].Wait()
+	var notes bytes.Buffer
+	sh.notifyJobs(&notes)
+
+	expected := "[1]   Exit 2                  sh -c 'exit 2'\n[2]-  Killed                  sleep 5\n"
+	if notes.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, notes.String())
+	}
+	if len(sh.jobs) != 1 || sh.jobs[0].id != 3 {
+		t.Errorf("Expected only job 3 to be left, got %d jobs", len(sh.jobs))
+	}
+}
+
+func TestForwardSignals(t *testing.T) {
+	sh, _, _ := newTestShell(t)
+	sh.Run("sleep 5 &")
+	j := sh.jobs[0]
+
+	stop := sh.forwardSignals()
+	defer stop()
+	sh.foreground.Store(j)
+
+	// The test process survives, as the shell catches the signal.
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor does it stop on the signals of job control.
+	for _, sig := range []syscall.Signal{syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU} {
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	j.Wait()
+	if status := handler.ExitStatus(j.Err()); status != 130 {
+		t.Errorf("Expected status 130, got %d", status)
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// openPty opens a pseudo-terminal. The shell gets the terminal end, the
+// test plays the user at the other.
+func openPty(t *testing.T) (master, tty *os.File) {
+	t.Helper()
+
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	master = os.NewFile(uintptr(fd), "/dev/ptmx")
+	t.Cleanup(func() { _ = master.Close() })
+
+	if err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatal(err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tty, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tty.Close() })
+	return master, tty
+}
+
+// screen collects what the shell writes to the terminal.
+type screen struct {
+	mu   sync.Mutex
+	text strings.Builder
+}
+
+func (s *screen) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.text.Write(p)
+}
+
+func (s *screen) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.text.String()
+}
+
+// waitFor waits until the prompt has been shown n times. Redrawing the
+// line while typing shows it again after a carriage return.
+func (s *screen) waitFor(t *testing.T, prompt string, n int) {
+	t.Helper()
+	shown := func() int {
+		text := s.String()
+		return strings.Count(text, prompt) - strings.Count(text, "\r"+prompt)
+	}
+	for deadline := time.Now().Add(5 * time.Second); shown() < n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected prompt %q %d times, got %q", prompt, n, s.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunInteractive(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	master, tty := openPty(t)
+
+	sh, _, stderr := newTestShell(t)
+	sh.stdin, sh.stdout = tty, tty
+	_ = sh.Set("PS1", "$ ")
+	_ = sh.Set("PS2", "> ")
+
+	var out screen
+	go func() { _, _ = io.Copy(&out, master) }()
+
+	done := make(chan error, 1)
+	go func() { done <- sh.RunInteractive() }()
+
+	type step struct {
+		wait  string
+		times int
+		keys  string
+	}
+	for _, s := range []step{
+		{"$ ", 1, "echo 'a\r"},
+		{"> ", 1, "b'\r"},
+		{"$ ", 2, "X=1\x1b\r"},
+		{"$ ", 3, "\x1b[A\x1b[A\r"},
+		{"$ ", 4, "echo $X\r"},
+		{"$ ", 5, "\x04"},
+	} {
+		out.waitFor(t, s.wait, s.times)
+		if _, err := io.WriteString(master, s.keys); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the shell to exit on Ctrl+D")
+	}
+
+	if screen := out.String(); strings.Count(screen, "a\r\nb\r\n") != 2 || !strings.Contains(screen, "1\r\n") {
+		t.Errorf("Expected the command to run twice and X to be set, got %q (stderr %q)", screen, stderr.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, historyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "echo 'a\\\nb'\nX=1\necho 'a\\\nb'\necho $X\n"; string(data) != expected {
+		t.Errorf("Expected history %q, got %q", expected, data)
+	}
+}
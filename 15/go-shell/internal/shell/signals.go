@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// forwardSignals keeps SIGINT and SIGQUIT from killing an interactive
+// shell and passes them on to the foreground job. Ctrl+C and Ctrl+\ reach
+// the job directly, as it owns the terminal; this covers signals sent to
+// the shell itself. SIGTSTP, SIGTTIN and SIGTTOU, with which Ctrl+Z and
+// the terminal stop jobs, are dropped, so that they never stop the shell.
+// The signals are caught rather than ignored, so the programs the shell
+// starts get their default dispositions back on exec.
+func (s *Shell) forwardSignals() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig != syscall.SIGINT && sig != syscall.SIGQUIT {
+					continue
+				}
+				if j := s.foreground.Load(); j != nil {
+					_ = j.Signal(sig.(syscall.Signal))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
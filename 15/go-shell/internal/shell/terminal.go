@@ -0,0 +1,26 @@
+package shell
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// setForeground gives the terminal tty to the process group pgid. The
+// shell is in the background when it takes the terminal back, and the
+// kernel would then stop it with SIGTTOU, so the signal is blocked on
+// this thread meanwhile. Blocking, unlike ignoring, leaves alone both the
+// shell's handler and the disposition that programs inherit.
+func setForeground(tty, pgid int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var set, old unix.Sigset_t
+	set.Val[0] = 1 << (unix.SIGTTOU - 1)
+	if err := unix.PthreadSigmask(unix.SIG_BLOCK, &set, &old); err != nil {
+		return err
+	}
+	defer func() { _ = unix.PthreadSigmask(unix.SIG_SETMASK, &old, nil) }()
+
+	return unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, pgid)
+}
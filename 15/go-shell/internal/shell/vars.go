@@ -0,0 +1,185 @@
+package shell
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// variable is a shell variable. Exported variables are kept in the
+// process environment too, so programs and PATH lookups see them. A
+// subshell keeps its own out of it.
+type variable struct {
+	value    string
+	exported bool
+}
+
+func environVars() map[string]*variable {
+	vars := make(map[string]*variable)
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			vars[name] = &variable{value: value, exported: true}
+		}
+	}
+	return vars
+}
+
+// Get returns a variable or a special parameter. It makes the shell a
+// parser.Env.
+func (s *Shell) Get(name string) (string, bool) {
+	switch name {
+	case "?":
+		return strconv.Itoa(s.status), true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "0":
+		if s.name == "" {
+			return shellName, true
+		}
+		return s.name, true
+	case "#":
+		return strconv.Itoa(len(s.args)), true
+	case "@", "*":
+		return strings.Join(s.args, " "), true
+	case "-":
+		return s.options.flags(), true
+	case "!":
+		if s.lastBackground == 0 {
+			return "", false
+		}
+		return strconv.Itoa(s.lastBackground), true
+	}
+
+	if name != "" && name[0] >= '1' && name[0] <= '9' {
+		n, err := strconv.Atoi(name)
+		if err != nil || n > len(s.args) {
+			return "", false
+		}
+		return s.args[n-1], true
+	}
+
+	v, ok := s.vars[name]
+	if !ok {
+		return "", false
+	}
+	return v.value, true
+}
+
+// Positional returns $1, $2 and so on for "$@".
+func (s *Shell) Positional() []string {
+	return s.args
+}
+
+func (s *Shell) Set(name, value string) error {
+	v, ok := s.vars[name]
+	if !ok {
+		v = &variable{}
+		s.vars[name] = v
+	}
+	v.value = value
+
+	if v.exported {
+		return s.setenv(name, value)
+	}
+	return nil
+}
+
+func (s *Shell) export(name string) error {
+	v, ok := s.vars[name]
+	if !ok {
+		v = &variable{}
+		s.vars[name] = v
+	}
+	v.exported = true
+	return s.setenv(name, v.value)
+}
+
+func (s *Shell) unset(name string) error {
+	v, ok := s.vars[name]
+	if !ok {
+		return nil
+	}
+	delete(s.vars, name)
+
+	if v.exported {
+		return s.unsetenv(name)
+	}
+	return nil
+}
+
+// frame holds the variables that a function call has made local, as they
+// were before the call: nil for those that did not exist.
+type frame map[string]*variable
+
+// local makes name a variable of the innermost function call, empty until
+// it is set.
+func (s *Shell) local(name string) {
+	f := s.frames[len(s.frames)-1]
+	if _, ok := f[name]; ok {
+		return
+	}
+
+	old := s.vars[name]
+	f[name] = old
+	v := &variable{exported: old != nil && old.exported}
+	s.vars[name] = v
+	if v.exported {
+		_ = s.setenv(name, "")
+	}
+}
+
+// restore puts back the variables that a returning function made local.
+func (s *Shell) restore(f frame) {
+	for name, old := range f {
+		if old == nil {
+			_ = s.unset(name)
+			continue
+		}
+
+		if cur := s.vars[name]; cur != nil && cur.exported && !old.exported {
+			_ = s.unsetenv(name)
+		}
+		s.vars[name] = old
+		if old.exported {
+			_ = s.setenv(name, old.value)
+		}
+	}
+}
+
+// exported lists the exported variables as NAME=value, sorted.
+func (s *Shell) exported() []string {
+	var list []string
+	for name, v := range s.vars {
+		if v.exported {
+			list = append(list, name+"="+v.value)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// setenv and unsetenv mirror an exported variable in the process
+// environment, which a subshell shares with the shell and so leaves alone.
+func (s *Shell) setenv(name, value string) error {
+	if s.isSubshell {
+		return nil
+	}
+	return os.Setenv(name, value)
+}
+
+func (s *Shell) unsetenv(name string) error {
+	if s.isSubshell {
+		return nil
+	}
+	return os.Unsetenv(name)
+}
+
+// environ is the environment of a program: the process's, or the exported
+// variables of a subshell.
+func (s *Shell) environ() []string {
+	if s.isSubshell {
+		return s.exported()
+	}
+	return os.Environ()
+}